@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/refImpl"
+	storeEncrypted "github.com/go-ocf/openapi-connector/store/encrypted"
+	"github.com/kelseyhightower/envconfig"
+)
+
+// Config of the re-encryption command. It shares the store configuration with the service.
+type Config struct {
+	Log   log.Config
+	Store refImpl.StoreConfig
+}
+
+// reencrypt rewrites every stored secret with the current key (LINKED_STORE_ENCRYPTION_KEY_ID).
+// Keys used by already stored values must remain in LINKED_STORE_ENCRYPTION_KEYS until it finishes.
+func main() {
+	var config Config
+	if err := envconfig.Process("", &config); err != nil {
+		log.Fatalf("cannot parse configuration: %v", err)
+	}
+	log.Setup(config.Log)
+	if !config.Store.Encryption.IsEnabled() {
+		log.Fatalf("cannot re-encrypt store: encryption is not configured")
+	}
+	keyring, err := storeEncrypted.NewKeyring(config.Store.Encryption)
+	if err != nil {
+		log.Fatalf("cannot create store keyring: %v", err)
+	}
+	ctx := context.Background()
+	backend, err := refImpl.NewBackendStore(ctx, config.Store)
+	if err != nil {
+		log.Fatalf("cannot create store: %v", err)
+	}
+	if err := storeEncrypted.NewStore(backend, keyring).Reencrypt(ctx); err != nil {
+		log.Fatalf("cannot re-encrypt store: %v", err)
+	}
+	log.Info("store was re-encrypted")
+}
@@ -10,6 +10,10 @@ import (
 	"github.com/go-ocf/kit/log"
 	"github.com/go-ocf/kit/security/acme"
 	"github.com/go-ocf/openapi-connector/service"
+	connectorStore "github.com/go-ocf/openapi-connector/store"
+	storeBoltdb "github.com/go-ocf/openapi-connector/store/boltdb"
+	storeEncrypted "github.com/go-ocf/openapi-connector/store/encrypted"
+	storeInmemory "github.com/go-ocf/openapi-connector/store/inmemory"
 	storeMongodb "github.com/go-ocf/openapi-connector/store/mongodb"
 	"github.com/panjf2000/ants"
 )
@@ -22,7 +26,15 @@ type Config struct {
 	GoRoutinePoolSize int         `envconfig:"GOROUTINE_POOL_SIZE" default:"16"`
 	DialAcme          acme.Config `envconfig:"DIAL_ACME"`
 	ListenAcme        acme.Config `envconfig:"LISTEN_ACME"`
-	StoreMongoDB      storeMongodb.Config
+	Store             StoreConfig
+}
+
+// StoreConfig selects and configures the backend holding linked clouds, linked accounts and subscriptions.
+type StoreConfig struct {
+	Backend    string `envconfig:"LINKED_STORE_BACKEND" default:"mongodb"`
+	MongoDB    storeMongodb.Config
+	BoltDB     storeBoltdb.Config
+	Encryption storeEncrypted.Config
 }
 
 //String return string representation of Config
@@ -31,6 +43,43 @@ func (c Config) String() string {
 	return fmt.Sprintf("config: \n%v\n", string(b))
 }
 
+// NewBackendStore creates the store selected by config.Backend without encryption of secrets.
+func NewBackendStore(ctx context.Context, config StoreConfig) (connectorStore.Store, error) {
+	switch config.Backend {
+	case "mongodb":
+		store, err := storeMongodb.NewStore(ctx, config.MongoDB)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create mongodb store %v", err)
+		}
+		return store, nil
+	case "boltdb":
+		store, err := storeBoltdb.NewStore(ctx, config.BoltDB)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create boltdb store %v", err)
+		}
+		return store, nil
+	case "inmemory":
+		return storeInmemory.NewStore(), nil
+	}
+	return nil, fmt.Errorf("cannot create store: unsupported backend %v", config.Backend)
+}
+
+// NewStore creates the store selected by config.Backend and wraps it with encryption of secrets when it is enabled.
+func NewStore(ctx context.Context, config StoreConfig) (connectorStore.Store, error) {
+	if !config.Encryption.IsEnabled() {
+		return NewBackendStore(ctx, config)
+	}
+	keyring, err := storeEncrypted.NewKeyring(config.Encryption)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create store keyring %v", err)
+	}
+	store, err := NewBackendStore(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return storeEncrypted.NewStore(store, keyring), nil
+}
+
 func Init(config Config) (*service.Server, error) {
 	log.Setup(config.Log)
 
@@ -49,9 +98,9 @@ func Init(config Config) (*service.Server, error) {
 		return nil, fmt.Errorf("cannot create resource nats subscriber %v", err)
 	}
 
-	store, err := storeMongodb.NewStore(context.Background(), config.StoreMongoDB)
+	store, err := NewStore(context.Background(), config.Store)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create mongodb store %v", err)
+		return nil, err
 	}
 
 	log.Info(config.String())
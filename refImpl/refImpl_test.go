@@ -18,6 +18,8 @@ func TestInit(t *testing.T) {
 	os.Setenv("SCOPES", "SCOPES")
 	os.Setenv("AUTH_URL", "AUTH_URL")
 	os.Setenv("TOKEN_URL", "TOKEN_URL")
+	os.Setenv("AUTH_JWKS_URL", "AUTH_JWKS_URL")
+	os.Setenv("JWKS_URL", "JWKS_URL")
 	err := envconfig.Process("", &config)
 	require.NoError(t, err)
 
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// addClientCredentialsLinkedAccount adds the linked account without the browser. The user is authenticated by the origin cloud
+// access token in the Authorization header, which is stored as the origin cloud token of the linked account, and the target
+// cloud token is obtained by the client credentials grant. The origin cloud token is renewed by the optional refresh_token
+// of the origin cloud. Without it the user reauthorizes the origin side of the linked account when the token expires,
+// the response reports it by HasRefreshToken and Expiry of OriginCloud.
+func (rh *RequestHandler) addClientCredentialsLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return http.StatusUnauthorized, err
+	}
+	c, err := parseOriginToken(rh.subManager.originValidator, store.AccessToken(token))
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return http.StatusUnauthorized, fmt.Errorf("cannot verify origin cloud access token: %v", err)
+	}
+
+	l := store.LinkedAccount{
+		UserID:    c.Subject,
+		TargetURL: r.FormValue("target_url"),
+		OriginCloud: store.OAuth{
+			AccessToken: store.AccessToken(token),
+		},
+	}
+	if c.ExpiresAt != 0 {
+		l.OriginCloud.Expiry = time.Unix(c.ExpiresAt, 0)
+	}
+	if refreshToken := r.FormValue("refresh_token"); refreshToken != "" {
+		l.OriginCloud, err = rh.verifyOriginRefreshToken(r.Context(), refreshToken, c.Subject)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+	if l.TargetURL == "" {
+		return http.StatusBadRequest, fmt.Errorf("invalid target_url")
+	}
+	linkedCloudID := r.FormValue("target_linked_cloud_id")
+	if linkedCloudID == "" {
+		return http.StatusBadRequest, fmt.Errorf("invalid target_linked_cloud_id")
+	}
+	linkedCloud, statusCode, err := rh.loadLinkedCloud(r.Context(), linkedCloudID)
+	if err != nil {
+		if statusCode == http.StatusNotFound {
+			statusCode = http.StatusBadRequest
+		}
+		return statusCode, err
+	}
+	if !linkedCloud.IsClientCredentials() {
+		return http.StatusBadRequest, fmt.Errorf("linked cloud %v doesn't use the client credentials grant", linkedCloud.ID)
+	}
+	l.TargetCloud, err = linkedCloud.ClientCredentialsToken(r.Context())
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("cannot get target cloud access token: %v", err)
+	}
+
+	l, statusCode, err = rh.insertLinkedAccount(r.Context(), l)
+	if err != nil {
+		return statusCode, err
+	}
+	err = writeJson(w, makeLinkedAccountResponse(l))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// verifyOriginRefreshToken refreshes the origin cloud tokens by the refresh token, so the refresh token is known to work,
+// and verifies that the refreshed access token belongs to the user.
+func (rh *RequestHandler) verifyOriginRefreshToken(ctx context.Context, refreshToken, userID string) (store.OAuth, error) {
+	// the expiry is set, otherwise the token is not refreshed
+	o := store.OAuth{RefreshToken: refreshToken, Expiry: time.Now()}
+	o, err := o.Refresh(ctx, rh.originCloud)
+	if err != nil {
+		return store.OAuth{}, fmt.Errorf("cannot refresh origin cloud token by refresh_token: %v", err)
+	}
+	refreshedUserID, err := verifyOriginToken(rh.subManager.originValidator, o.AccessToken)
+	if err != nil {
+		return store.OAuth{}, fmt.Errorf("cannot verify refreshed origin cloud access token: %v", err)
+	}
+	if refreshedUserID != userID {
+		return store.OAuth{}, fmt.Errorf("refresh_token belongs to another user")
+	}
+	return o, nil
+}
+
+func (rh *RequestHandler) AddClientCredentialsLinkedAccount(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.addClientCredentialsLinkedAccount(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot add linked account: %v", err), statusCode, w)
+	}
+}
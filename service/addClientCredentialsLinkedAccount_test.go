@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddClientCredentialsLinkedAccount(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	userToken := newTestToken(t, key, newTestUserClaims("user0", ""))
+	refreshedUserTokens := map[string]string{
+		"originRefreshToken": newTestToken(t, key, newTestUserClaims("user0", "")),
+		"otherRefreshToken":  newTestToken(t, key, newTestUserClaims("user1", "")),
+	}
+	targetServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			if r.PostForm.Get("grant_type") == "refresh_token" {
+				token, ok := refreshedUserTokens[r.PostForm.Get("refresh_token")]
+				if !ok {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":"invalid_grant"}`))
+					return
+				}
+				fmt.Fprintf(w, `{"access_token":"%v","refresh_token":"%v","token_type":"bearer","expires_in":3600}`, token, r.PostForm.Get("refresh_token"))
+				return
+			}
+			if r.PostForm.Get("grant_type") != "client_credentials" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"targetAccessToken","token_type":"bearer","expires_in":3600}`))
+		case "/devices/subscriptions":
+			w.Write([]byte(`{"subscriptionId":"testSubscriptionID"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer targetServer.Close()
+
+	newCloud := func(id string, grantType store.GrantType) store.LinkedCloud {
+		return store.LinkedCloud{
+			ID:           id,
+			ClientID:     "testClientID",
+			ClientSecret: "testClientSecret",
+			Scopes:       []string{"testScope"},
+			GrantType:    grantType,
+			Endpoint:     store.Endpoint{AuthUrl: targetServer.URL + "/authorize", TokenUrl: targetServer.URL + "/token"},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		token         string
+		linkedCloudID string
+		refreshToken  string
+		wantCode      int
+	}{
+		{name: "without token", linkedCloudID: "clientCredentials", wantCode: http.StatusUnauthorized},
+		{name: "invalid token", token: "invalid", linkedCloudID: "clientCredentials", wantCode: http.StatusUnauthorized},
+		{name: "unknown linked cloud", token: userToken, linkedCloudID: "unknown", wantCode: http.StatusBadRequest},
+		{name: "authorization code linked cloud", token: userToken, linkedCloudID: "authorizationCode", wantCode: http.StatusBadRequest},
+		{name: "valid", token: userToken, linkedCloudID: "clientCredentials", wantCode: http.StatusOK},
+		{name: "invalid refresh token", token: userToken, linkedCloudID: "clientCredentials", refreshToken: "invalid", wantCode: http.StatusBadRequest},
+		{name: "refresh token of another user", token: userToken, linkedCloudID: "clientCredentials", refreshToken: "otherRefreshToken", wantCode: http.StatusBadRequest},
+		{name: "valid with refresh token", token: userToken, linkedCloudID: "clientCredentials", refreshToken: "originRefreshToken", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := inmemory.NewStore()
+			require.NoError(t, s.InsertLinkedCloud(ctx, newCloud("clientCredentials", store.GrantType_CLIENT_CREDENTIALS)))
+			require.NoError(t, s.InsertLinkedCloud(ctx, newCloud("authorizationCode", "")))
+			originCloud := newCloud("origin", "")
+			validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+			subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, validator, 0, 0)
+			pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+			rh := NewRequestHandler(originCloud, "https://connector/callback", subManager, nil, nil, nil, s, pendingLinks, newTestAuthorizer(jwks.URL), nil, nil)
+
+			form := url.Values{}
+			form.Set("target_url", targetServer.URL)
+			form.Set("target_linked_cloud_id", tt.linkedCloudID)
+			if tt.refreshToken != "" {
+				form.Set("refresh_token", tt.refreshToken)
+			}
+			r := httptest.NewRequest(http.MethodPost, uri.LinkedAccounts, strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			NewHTTP(rh).Handler.ServeHTTP(w, r)
+			require.Equal(t, tt.wantCode, w.Code)
+
+			var h LinkedAccountsHandler
+			require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{}, &h))
+			if tt.wantCode != http.StatusOK {
+				assert.Empty(t, h.linkedAccounts)
+				return
+			}
+			require.Len(t, h.linkedAccounts, 1)
+			l := h.linkedAccounts[0]
+			assert.Equal(t, "user0", l.UserID)
+			if tt.refreshToken != "" {
+				assert.Equal(t, store.AccessToken(refreshedUserTokens[tt.refreshToken]), l.OriginCloud.AccessToken)
+				assert.Equal(t, tt.refreshToken, l.OriginCloud.RefreshToken)
+			} else {
+				assert.Equal(t, store.AccessToken(userToken), l.OriginCloud.AccessToken)
+				assert.Empty(t, l.OriginCloud.RefreshToken)
+			}
+			assert.False(t, l.OriginCloud.Expiry.IsZero())
+			assert.Equal(t, "clientCredentials", l.TargetCloud.LinkedCloudID)
+			assert.Equal(t, store.AccessToken("targetAccessToken"), l.TargetCloud.AccessToken)
+			assert.Contains(t, w.Body.String(), l.ID)
+
+			var sh SubscriptionsHandler
+			require.NoError(t, s.LoadSubscriptions(ctx, []store.SubscriptionQuery{{LinkedAccountID: l.ID, Type: store.Type_Devices}}, &sh))
+			require.Len(t, sh.subscriptions, 1)
+			assert.Equal(t, "testSubscriptionID", sh.subscriptions[0].SubscriptionID)
+		})
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"net/http"
 
 	"github.com/go-ocf/openapi-connector/store"
-	"github.com/patrickmn/go-cache"
 	"golang.org/x/oauth2"
 )
 
@@ -55,26 +54,36 @@ func (rh *RequestHandler) HandleOAuth(w http.ResponseWriter, r *http.Request, da
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
+	if linkedCloud.IsClientCredentials() {
+		return http.StatusBadRequest, fmt.Errorf("linked cloud %v uses the client credentials grant, the linked account must be added by POST", linkedCloud.ID)
+	}
 	oauth := linkedCloud.ToOAuth2Config()
 	oauth.RedirectURL = rh.oauthCallback
 	t, err := generateRandomString(32)
 	if err != nil {
 		return http.StatusInternalServerError, fmt.Errorf("cannot generate random token")
 	}
-	err = rh.provisionCache.Add(t, data, cache.DefaultExpiration)
-	if err != nil {
-		return http.StatusInternalServerError, fmt.Errorf("cannot store key - collision")
+	opts := []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}
+	opts = append(opts, linkedCloud.AuthCodeOptions()...)
+	data.CodeVerifier = ""
+	if linkedCloud.PKCE {
+		data.CodeVerifier, err = newCodeVerifier()
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("cannot generate code verifier: %v", err)
+		}
+		opts = append(opts, codeChallengeOptions(data.CodeVerifier)...)
 	}
-	url := oauth.AuthCodeURL(t, oauth2.AccessTypeOffline)
-	if linkedCloud.Audience != "" {
-		//"https://portal.shared.pluggedin.cloud"
-		url = oauth.AuthCodeURL(t, oauth2.AccessTypeOffline, oauth2.SetAuthURLParam("audience", linkedCloud.Audience))
+	err = rh.pendingLinks.Add(r.Context(), t, data)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("cannot store pending link: %v", err)
 	}
+	url := oauth.AuthCodeURL(t, opts...)
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 	return http.StatusOK, nil
 }
 
-func (rh *RequestHandler) addLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+// parseNewLinkedAccount returns the linked account requested by params target_url and target_linked_cloud_id.
+func parseNewLinkedAccount(r *http.Request) (store.LinkedAccount, error) {
 	l := store.LinkedAccount{
 		TargetURL: r.FormValue("target_url"),
 		TargetCloud: store.OAuth{
@@ -82,13 +91,26 @@ func (rh *RequestHandler) addLinkedAccount(w http.ResponseWriter, r *http.Reques
 		},
 	}
 	if l.TargetURL == "" {
-		return http.StatusBadRequest, fmt.Errorf("invalid target_url")
+		return l, fmt.Errorf("invalid target_url")
 	}
 	if l.TargetCloud.LinkedCloudID == "" {
-		return http.StatusBadRequest, fmt.Errorf("invalid target_linked_cloud_id")
+		return l, fmt.Errorf("invalid target_linked_cloud_id")
+	}
+	return l, nil
+}
+
+func (rh *RequestHandler) addLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+	l, err := parseNewLinkedAccount(r)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	redirectURI, err := rh.parseRedirectURI(r)
+	if err != nil {
+		return http.StatusBadRequest, err
 	}
 
-	data := LinkedAccountData{LinkedAccount: l}
+	data := LinkedAccountData{LinkedAccount: l, RedirectURI: redirectURI}
 	return rh.HandleOAuth(w, r, data)
 }
 
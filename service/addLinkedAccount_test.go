@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleOAuthAuthParams(t *testing.T) {
+	originCloud := store.LinkedCloud{
+		ClientID:   "testClientID",
+		Scopes:     []string{"testScope"},
+		Audience:   "testAudience",
+		AuthParams: map[string]string{"prompt": "consent", "acr_values": "testAcr"},
+		Endpoint:   store.Endpoint{AuthUrl: "https://origin/authorize", TokenUrl: "https://origin/token"},
+	}
+	pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+	rh := NewRequestHandler(originCloud, "https://connector/callback", nil, nil, nil, nil, nil, pendingLinks, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	_, err := rh.HandleOAuth(w, httptest.NewRequest(http.MethodGet, "/", nil), LinkedAccountData{})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+	u, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "testAudience", q.Get("audience"))
+	assert.Equal(t, "consent", q.Get("prompt"))
+	assert.Equal(t, "testAcr", q.Get("acr_values"))
+	assert.Equal(t, "testClientID", q.Get("client_id"))
+}
+
+func TestHandleLinkedAccountSendsTokenParams(t *testing.T) {
+	var form url.Values
+	var basicAuth bool
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+		_, _, basicAuth = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"testAccessToken","refresh_token":"testRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		TokenParams:  map[string]string{"resource": "testResource"},
+		Endpoint:     store.Endpoint{AuthUrl: tokenServer.URL + "/authorize", TokenUrl: tokenServer.URL + "/token", AuthStyle: store.AuthStyle_PARAMS},
+	}))
+	rh := NewRequestHandler(store.LinkedCloud{}, "https://connector/callback", nil, nil, nil, nil, s, nil, nil, nil, nil)
+
+	_, err := rh.HandleLinkedAccount(ctx, LinkedAccountData{
+		LinkedAccount: store.LinkedAccount{TargetCloud: store.OAuth{LinkedCloudID: "testID"}},
+		State:         LinkedAccountState_PROVISIONED_ORIGIN_CLOUD,
+	}, "testCode")
+	require.NoError(t, err)
+	assert.Equal(t, "testResource", form.Get("resource"))
+	assert.Equal(t, "testCode", form.Get("code"))
+	assert.Equal(t, "testClientSecret", form.Get("client_secret"))
+	assert.False(t, basicAuth)
+}
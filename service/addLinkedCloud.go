@@ -36,6 +36,12 @@ func (rh *RequestHandler) addLinkedCloud(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		return http.StatusBadRequest, fmt.Errorf("cannot decode body: %v", err)
 	}
+	if l.Issuer != "" {
+		l, err = rh.discovery.Resolve(r.Context(), l)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
 	if l.ID == "" {
 		uuid, err := uuid.NewV4()
 		if err != nil {
@@ -43,16 +49,49 @@ func (rh *RequestHandler) addLinkedCloud(w http.ResponseWriter, r *http.Request)
 		}
 		l.ID = uuid.String()
 		err = rh.store.InsertLinkedCloud(r.Context(), l)
+		if err != nil {
+			return http.StatusBadRequest, err
+		}
 	} else {
-		err = rh.store.UpdateLinkedCloud(r.Context(), l)
+		statusCode, err := rh.updateLinkedCloud(r, &l)
+		if err != nil {
+			return statusCode, err
+		}
+	}
+	w.Header().Set(ETagHeader, makeETag(l.Version))
+	err = writeJson(w, makeLinkedCloudResponse(l))
+	if err != nil {
+		return http.StatusInternalServerError, err
 	}
+	return http.StatusOK, nil
+}
+
+// updateLinkedCloud replaces the stored linked cloud when its version matches the If-Match header.
+// Without the header the currently stored linked cloud is replaced.
+func (rh *RequestHandler) updateLinkedCloud(r *http.Request, l *store.LinkedCloud) (int, error) {
+	version, ok, err := parseIfMatch(r)
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
-	err = writeJson(w, l)
+	if !ok {
+		current, statusCode, err := rh.loadLinkedCloud(r.Context(), l.ID)
+		if err != nil {
+			return statusCode, err
+		}
+		version = current.Version
+	}
+	l.Version = version
+	err = rh.store.UpdateLinkedCloud(r.Context(), *l)
+	if store.IsConflictError(err) {
+		if ok {
+			return http.StatusPreconditionFailed, err
+		}
+		return http.StatusConflict, err
+	}
 	if err != nil {
-		return http.StatusInternalServerError, err
+		return http.StatusBadRequest, err
 	}
+	l.Version++
 	return http.StatusOK, nil
 }
 
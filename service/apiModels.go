@@ -0,0 +1,188 @@
+package service
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// LinkedCloudResponse is the representation of store.LinkedCloud returned by the REST API. ClientSecret,
+// the private key of the HTTP client certificate and credentials of the proxy are never exposed.
+type LinkedCloudResponse struct {
+	ID          string            `json:"ID"`
+	Name        string            `json:"Name"`
+	ClientID    string            `json:"ClientId"`
+	Scopes      []string          `json:"Scopes"`
+	Endpoint    store.Endpoint    `json:"Endpoint"`
+	Audience    string            `json:"Audience,omitempty"`
+	AuthParams  map[string]string `json:"AuthParams,omitempty"`
+	TokenParams map[string]string `json:"TokenParams,omitempty"`
+	JwksURL     string            `json:"JwksUrl,omitempty"`
+	Issuer      string            `json:"Issuer,omitempty"`
+	PKCE        bool              `json:"PKCE"`
+	GrantType   store.GrantType   `json:"GrantType,omitempty"`
+	HTTP        store.HTTPConfig  `json:"HTTP"`
+	// Version is the ETag of the linked cloud without quotes.
+	Version uint64 `json:"Version"`
+}
+
+// makeProxyResponse removes credentials of the proxy URL. The URL which cannot be parsed is not returned at all.
+func makeProxyResponse(proxy string) string {
+	if proxy == "" {
+		return ""
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	return u.String()
+}
+
+func makeLinkedCloudResponse(l store.LinkedCloud) LinkedCloudResponse {
+	l.HTTP.PrivateKey = ""
+	l.HTTP.Proxy = makeProxyResponse(l.HTTP.Proxy)
+	return LinkedCloudResponse{
+		ID:          l.ID,
+		Name:        l.Name,
+		ClientID:    l.ClientID,
+		Scopes:      l.Scopes,
+		Endpoint:    l.Endpoint,
+		Audience:    l.Audience,
+		AuthParams:  l.AuthParams,
+		TokenParams: l.TokenParams,
+		JwksURL:     l.JwksURL,
+		Issuer:      l.Issuer,
+		PKCE:        l.PKCE,
+		GrantType:   l.GrantType,
+		HTTP:        l.HTTP,
+		Version:     l.Version,
+	}
+}
+
+func makeLinkedCloudsResponse(linkedClouds []store.LinkedCloud) []LinkedCloudResponse {
+	resp := make([]LinkedCloudResponse, 0, len(linkedClouds))
+	for _, l := range linkedClouds {
+		resp = append(resp, makeLinkedCloudResponse(l))
+	}
+	return resp
+}
+
+type TokenStatus string
+
+const (
+	// TokenStatus_VALID access token can be used
+	TokenStatus_VALID TokenStatus = "valid"
+	// TokenStatus_EXPIRED access token is expired and must be refreshed before use
+	TokenStatus_EXPIRED TokenStatus = "expired"
+	// TokenStatus_MISSING access token has not been obtained
+	TokenStatus_MISSING TokenStatus = "missing"
+)
+
+// OAuthResponse is the representation of store.OAuth returned by the REST API. Tokens are never exposed.
+type OAuthResponse struct {
+	LinkedCloudID   string      `json:"LinkedCloudId,omitempty"`
+	Status          TokenStatus `json:"Status"`
+	Expiry          *time.Time  `json:"Expiry,omitempty"`
+	HasRefreshToken bool        `json:"HasRefreshToken"`
+}
+
+func makeOAuthResponse(o store.OAuth) OAuthResponse {
+	resp := OAuthResponse{
+		LinkedCloudID:   o.LinkedCloudID,
+		Status:          TokenStatus_VALID,
+		HasRefreshToken: o.RefreshToken != "",
+	}
+	if !o.Expiry.IsZero() {
+		expiry := o.Expiry
+		resp.Expiry = &expiry
+	}
+	switch {
+	case o.AccessToken == "":
+		resp.Status = TokenStatus_MISSING
+	case !o.IsValidAccessToken():
+		resp.Status = TokenStatus_EXPIRED
+	}
+	return resp
+}
+
+// LinkedAccountResponse is the representation of store.LinkedAccount returned by the REST API.
+type LinkedAccountResponse struct {
+	ID          string                    `json:"ID"`
+	UserID      string                    `json:"UserId"`
+	TargetURL   string                    `json:"TargetURL"`
+	TargetCloud OAuthResponse             `json:"TargetCloud"`
+	OriginCloud OAuthResponse             `json:"OriginCloud"`
+	Status      store.LinkedAccountStatus `json:"Status"`
+	LastError   string                    `json:"LastError,omitempty"`
+	LastErrorAt *time.Time                `json:"LastErrorAt,omitempty"`
+	// StatusChangedAt is missing when the status was never changed.
+	StatusChangedAt *time.Time `json:"StatusChangedAt,omitempty"`
+	// TargetReauthRequired the linked account must be reauthorized with side target.
+	TargetReauthRequired bool `json:"TargetReauthRequired,omitempty"`
+}
+
+func makeTimeResponse(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func makeLinkedAccountResponse(l store.LinkedAccount) LinkedAccountResponse {
+	if l.Status == "" {
+		l.Status = store.LinkedAccountStatus_ACTIVE
+	}
+	return LinkedAccountResponse{
+		ID:                   l.ID,
+		UserID:               l.UserID,
+		TargetURL:            l.TargetURL,
+		TargetCloud:          makeOAuthResponse(l.TargetCloud),
+		OriginCloud:          makeOAuthResponse(l.OriginCloud),
+		Status:               l.Status,
+		LastError:            l.LastError,
+		LastErrorAt:          makeTimeResponse(l.LastErrorAt),
+		StatusChangedAt:      makeTimeResponse(l.StatusChangedAt),
+		TargetReauthRequired: l.TargetReauthRequired,
+	}
+}
+
+func makeLinkedAccountsResponse(linkedAccounts []store.LinkedAccount) []LinkedAccountResponse {
+	resp := make([]LinkedAccountResponse, 0, len(linkedAccounts))
+	for _, l := range linkedAccounts {
+		resp = append(resp, makeLinkedAccountResponse(l))
+	}
+	return resp
+}
+
+// DeviceAuthorizationResponse is the state of the account linking by the device authorization grant returned by the REST API.
+// The user enters the UserCode at the VerificationURI of the cloud of the Side. LinkedAccountID is set when the linking is finished.
+type DeviceAuthorizationResponse struct {
+	ID                      string            `json:"ID"`
+	Side                    LinkedAccountSide `json:"Side,omitempty"`
+	UserCode                string            `json:"UserCode,omitempty"`
+	VerificationURI         string            `json:"VerificationUri,omitempty"`
+	VerificationURIComplete string            `json:"VerificationUriComplete,omitempty"`
+	// Interval is the minimal time in seconds between polls of the device authorization.
+	Interval        int64  `json:"Interval,omitempty"`
+	LinkedAccountID string `json:"LinkedAccountId,omitempty"`
+}
+
+func makeDeviceAuthorizationResponse(id string, data LinkedAccountData, linkedAccountID string) DeviceAuthorizationResponse {
+	if linkedAccountID != "" {
+		return DeviceAuthorizationResponse{ID: id, LinkedAccountID: linkedAccountID}
+	}
+	side := LinkedAccountSide_ORIGIN
+	if data.State == LinkedAccountState_PROVISIONED_ORIGIN_CLOUD {
+		side = LinkedAccountSide_TARGET
+	}
+	return DeviceAuthorizationResponse{
+		ID:                      id,
+		Side:                    side,
+		UserCode:                data.DeviceAuthorization.UserCode,
+		VerificationURI:         data.DeviceAuthorization.VerificationURI,
+		VerificationURIComplete: data.DeviceAuthorization.VerificationURIComplete,
+		Interval:                data.DeviceAuthorization.Interval,
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	kitStrings "github.com/go-ocf/kit/strings"
+)
+
+// AuthorizationConfig configures validation of the bearer tokens accepted by the management API.
+type AuthorizationConfig struct {
+	JwksURL    string `envconfig:"AUTH_JWKS_URL" required:"true"`
+	Audience   string `envconfig:"AUTH_AUDIENCE"`
+	Issuer     string `envconfig:"AUTH_ISSUER"`
+	AdminScope string `envconfig:"AUTH_ADMIN_SCOPE" default:"openapi-connector:admin"`
+}
+
+// TokenValidator verifies the signature of a token and fills claims.
+type TokenValidator interface {
+	ParseWithClaims(token string, claims jwtgo.Claims) error
+}
+
+// Claims of a bearer token accepted by the management API.
+type Claims struct {
+	kitJwt.StandardClaims
+	Scope interface{} `json:"scope"`
+
+	audience string
+	issuer   string
+}
+
+// Valid checks expiration, audience and issuer of the token.
+func (c *Claims) Valid() error {
+	if err := c.StandardClaims.Valid(); err != nil {
+		return err
+	}
+	if c.Subject == "" {
+		return fmt.Errorf("missing subject")
+	}
+	if c.audience != "" && !kitStrings.SliceContains(c.GetAudience(), c.audience) {
+		return fmt.Errorf("invalid audience")
+	}
+	if c.issuer != "" && c.Issuer != c.issuer {
+		return fmt.Errorf("invalid issuer")
+	}
+	return nil
+}
+
+// GetScope returns scopes of the token, which are provided as a space-delimited string or as an array.
+func (c *Claims) GetScope() []string {
+	switch v := c.Scope.(type) {
+	case string:
+		return strings.Fields(v)
+	default:
+		return kitStrings.ToSlice(v)
+	}
+}
+
+// HasScope reports whether the token was granted the scope.
+func (c *Claims) HasScope(scope string) bool {
+	return kitStrings.SliceContains(c.GetScope(), scope)
+}
+
+type claimsKey struct{}
+
+// ClaimsFromContext returns claims of the authenticated caller.
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	c, ok := ctx.Value(claimsKey{}).(*Claims)
+	return c, ok
+}
+
+// Authorizer authenticates callers of the management API by the bearer token.
+type Authorizer struct {
+	validator  TokenValidator
+	audience   string
+	issuer     string
+	adminScope string
+}
+
+// NewAuthorizer creates an authorizer from the configuration.
+func NewAuthorizer(config AuthorizationConfig, validator TokenValidator) *Authorizer {
+	return &Authorizer{
+		validator:  validator,
+		audience:   config.Audience,
+		issuer:     config.Issuer,
+		adminScope: config.AdminScope,
+	}
+}
+
+// IsAdmin reports whether the caller can manage linked clouds and all linked accounts.
+func (a *Authorizer) IsAdmin(c *Claims) bool {
+	return a.adminScope != "" && c.HasScope(a.adminScope)
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", fmt.Errorf("missing bearer token")
+	}
+	return strings.TrimSpace(auth[7:]), nil
+}
+
+func (a *Authorizer) authenticate(r *http.Request) (*Claims, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+	c := Claims{
+		audience: a.audience,
+		issuer:   a.issuer,
+	}
+	err = a.validator.ParseWithClaims(token, &c)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Authenticated rejects requests without a valid bearer token and stores its claims to the request context.
+func (a *Authorizer) Authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			logAndWriteErrorResponse(fmt.Errorf("cannot authenticate request: %v", err), http.StatusUnauthorized, w)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, c)))
+	}
+}
+
+// Admin rejects requests of callers without the admin scope.
+func (a *Authorizer) Admin(next http.HandlerFunc) http.HandlerFunc {
+	return a.Authenticated(func(w http.ResponseWriter, r *http.Request) {
+		c, _ := ClaimsFromContext(r.Context())
+		if !a.IsAdmin(c) {
+			logAndWriteErrorResponse(fmt.Errorf("cannot authorize request: missing scope %v", a.adminScope), http.StatusForbidden, w)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/go-ocf/kit/codec/json"
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeyID = "testKeyID"
+
+func newTestJwks(t *testing.T) (*httptest.Server, *rsa.PrivateKey) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","use":"sig","alg":"RS256","kid":"%v","n":"%v","e":"%v"}]}`,
+		testKeyID,
+		base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(jwks))
+	}))
+	return server, key
+}
+
+func newTestToken(t *testing.T, key *rsa.PrivateKey, claims jwtgo.MapClaims) string {
+	token := jwtgo.NewWithClaims(jwtgo.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+	v, err := token.SignedString(key)
+	require.NoError(t, err)
+	return v
+}
+
+func newTestUserClaims(sub string, scope string) jwtgo.MapClaims {
+	return jwtgo.MapClaims{
+		"sub":   sub,
+		"aud":   "testAudience",
+		"iss":   "testIssuer",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": scope,
+	}
+}
+
+func newTestAuthorizer(jwksURL string) *Authorizer {
+	return NewAuthorizer(AuthorizationConfig{
+		JwksURL:    jwksURL,
+		Audience:   "testAudience",
+		Issuer:     "testIssuer",
+		AdminScope: "testAdmin",
+	}, kitJwt.NewValidator(jwksURL, tls.Config{}))
+}
+
+func TestAuthorizer(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+	a := newTestAuthorizer(server.URL)
+
+	expired := newTestUserClaims("testUser", "")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	invalidAudience := newTestUserClaims("testUser", "")
+	invalidAudience["aud"] = "invalid"
+	invalidIssuer := newTestUserClaims("testUser", "")
+	invalidIssuer["iss"] = "invalid"
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		header    string
+		wantUser  int
+		wantAdmin int
+	}{
+		{
+			name:      "missing token",
+			wantUser:  http.StatusUnauthorized,
+			wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:      "invalid signature",
+			header:    "Bearer " + newTestToken(t, otherKey, newTestUserClaims("testUser", "")),
+			wantUser:  http.StatusUnauthorized,
+			wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:      "expired",
+			header:    "Bearer " + newTestToken(t, key, expired),
+			wantUser:  http.StatusUnauthorized,
+			wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:      "invalid audience",
+			header:    "Bearer " + newTestToken(t, key, invalidAudience),
+			wantUser:  http.StatusUnauthorized,
+			wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:      "invalid issuer",
+			header:    "Bearer " + newTestToken(t, key, invalidIssuer),
+			wantUser:  http.StatusUnauthorized,
+			wantAdmin: http.StatusUnauthorized,
+		},
+		{
+			name:      "user",
+			header:    "Bearer " + newTestToken(t, key, newTestUserClaims("testUser", "openid")),
+			wantUser:  http.StatusOK,
+			wantAdmin: http.StatusForbidden,
+		},
+		{
+			name:      "admin",
+			header:    "bearer " + newTestToken(t, key, newTestUserClaims("testUser", "openid testAdmin")),
+			wantUser:  http.StatusOK,
+			wantAdmin: http.StatusOK,
+		},
+	}
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		c, ok := ClaimsFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, "testUser", c.Subject)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			a.Authenticated(ok)(w, r)
+			assert.Equal(t, tt.wantUser, w.Code)
+
+			w = httptest.NewRecorder()
+			a.Admin(ok)(w, r)
+			assert.Equal(t, tt.wantAdmin, w.Code)
+		})
+	}
+}
+
+func TestLinkedAccountsAreScopedToUser(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	for _, user := range []string{"user0", "user1"} {
+		require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+			ID:          user,
+			UserID:      user,
+			TargetURL:   "testTargetURL",
+			TargetCloud: store.OAuth{LinkedCloudID: "testID", AccessToken: "testAccessToken"},
+		}))
+	}
+	rh := NewRequestHandler(store.LinkedCloud{}, "", nil, nil, nil, nil, s, nil, newTestAuthorizer(server.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+
+	retrieve := func(token string) []LinkedAccountResponse {
+		r := httptest.NewRequest(http.MethodGet, uri.LinkedAccounts+"/retrieve", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp []LinkedAccountResponse
+		require.NoError(t, json.Decode(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	user0 := newTestToken(t, key, newTestUserClaims("user0", ""))
+	admin := newTestToken(t, key, newTestUserClaims("admin", "testAdmin"))
+
+	resp := retrieve(user0)
+	require.Len(t, resp, 1)
+	assert.Equal(t, "user0", resp[0].ID)
+	assert.Len(t, retrieve(admin), 2)
+
+	r := httptest.NewRequest(http.MethodDelete, uri.LinkedAccounts+"/user1", nil)
+	r.Header.Set("Authorization", "Bearer "+user0)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, retrieve(admin), 2)
+
+	r = httptest.NewRequest(http.MethodGet, uri.LinkedClouds, nil)
+	r.Header.Set("Authorization", "Bearer "+user0)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -3,24 +3,36 @@ package service
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/go-ocf/openapi-connector/store"
 
 	"github.com/go-ocf/kit/net/grpc"
 )
 
-//Config represent application configuration
+// Config represent application configuration
 type Config struct {
 	grpc.Config
-	AuthServerAddr        string `envconfig:"AUTH_SERVER_ADDRESS" default:"127.0.0.1:9100"`
-	ResourceAggregateAddr string `envconfig:"RESOURCE_AGGREGATE_ADDRESS"  default:"127.0.0.1:9100"`
-	FQDN                  string `envconfig:"FQDN" default:"openapi.pluggedin.cloud"`
-	OAuthCallback         string `envconfig:"OAUTH_CALLBACK" required:"true"`
-	EventsURL             string `envconfig:"EVENTS_URL" required:"true"`
-	OriginCloud           store.LinkedCloud
+	AuthServerAddr        string        `envconfig:"AUTH_SERVER_ADDRESS" default:"127.0.0.1:9100"`
+	ResourceAggregateAddr string        `envconfig:"RESOURCE_AGGREGATE_ADDRESS"  default:"127.0.0.1:9100"`
+	FQDN                  string        `envconfig:"FQDN" default:"openapi.pluggedin.cloud"`
+	OAuthCallback         string        `envconfig:"OAUTH_CALLBACK" required:"true"`
+	EventsURL             string        `envconfig:"EVENTS_URL" required:"true"`
+	PendingLinkExpiration time.Duration `envconfig:"PENDING_LINK_EXPIRATION" default:"5m"`
+	RedirectURIs          []string      `envconfig:"REDIRECT_URIS"`
+	// EventTimestampWindow events whose Event-Timestamp differs from the current time by more are rejected. Zero disables the check.
+	EventTimestampWindow time.Duration `envconfig:"EVENT_TIMESTAMP_WINDOW" default:"5m"`
+	// EventReorderTimeout an event which arrives before events with lower sequence numbers waits for them at most the timeout,
+	// then the missing events are skipped. The first events of a subscription wait for it too, so the lowest of them is applied first.
+	// Zero disables the reordering.
+	EventReorderTimeout time.Duration `envconfig:"EVENT_REORDER_TIMEOUT" default:"5s"`
+	OriginCloud         store.LinkedCloud
+	Authorization       AuthorizationConfig
+	TokenRefresher      TokenRefresherConfig
+	Discovery           DiscoveryConfig
 }
 
-//String return string representation of Config
+// String return string representation of Config
 func (c Config) String() string {
 	b, _ := json.MarshalIndent(c, "", "  ")
 	return fmt.Sprintf("config: \n%v\n", string(b))
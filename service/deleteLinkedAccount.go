@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/go-ocf/kit/log"
 	"github.com/go-ocf/openapi-connector/store"
 
 	"github.com/gorilla/mux"
@@ -12,8 +14,12 @@ import (
 func (rh *RequestHandler) deleteLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
 	linkedAccountId, _ := mux.Vars(r)[linkedAccountIdKey]
 
+	query, err := rh.linkedAccountsQuery(r.Context(), store.Query{ID: linkedAccountId})
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
 	var h LinkedAccountHandler
-	err := rh.store.LoadLinkedAccounts(r.Context(), store.Query{ID: linkedAccountId}, &h)
+	err = rh.store.LoadLinkedAccounts(r.Context(), query, &h)
 	if err != nil {
 		return http.StatusBadRequest, fmt.Errorf("cannot load linked account: %v", err)
 	}
@@ -21,21 +27,46 @@ func (rh *RequestHandler) deleteLinkedAccount(w http.ResponseWriter, r *http.Req
 		return http.StatusBadRequest, fmt.Errorf("cannot load linked account: not found")
 	}
 
+	err = rh.removeLinkedAccount(r.Context(), h.linkedAccount)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+// removeLinkedAccount stops subscriptions of the linked account, revokes its tokens and removes it from the store.
+func (rh *RequestHandler) removeLinkedAccount(ctx context.Context, l store.LinkedAccount) error {
 	var errors []error
 
-	err = rh.subManager.StopSubscriptions(r.Context(), h.linkedAccount)
+	err := rh.subManager.StopSubscriptions(ctx, l)
 	if err != nil {
 		errors = append(errors, err)
 	}
 
-	err = rh.store.RemoveLinkedAccount(r.Context(), linkedAccountId)
+	rh.revokeTokens(ctx, l)
+
+	err = rh.store.RemoveLinkedAccount(ctx, l.ID)
 	if err != nil {
 		errors = append(errors, err)
 	}
 	if len(errors) > 0 {
-		return http.StatusInternalServerError, fmt.Errorf("%v", errors)
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
+
+// revokeTokens revokes tokens of the linked account at both clouds. Failures are logged, they don't prevent the delete.
+func (rh *RequestHandler) revokeTokens(ctx context.Context, l store.LinkedAccount) {
+	// tokens could be refreshed by StopSubscriptions
+	var h LinkedAccountHandler
+	err := rh.store.LoadLinkedAccounts(ctx, store.Query{ID: l.ID}, &h)
+	if err == nil && h.ok {
+		l = h.linkedAccount
+	}
+	err = l.RevokeTokens(ctx, rh.store, rh.originCloud)
+	if err != nil {
+		log.Errorf("cannot revoke tokens of linked account %v: %v", l.ID, err)
 	}
-	return http.StatusOK, nil
 }
 
 func (rh *RequestHandler) DeleteLinkedAccount(w http.ResponseWriter, r *http.Request) {
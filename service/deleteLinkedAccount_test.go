@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteLinkedAccountRevokesTokens(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	var revoked []string
+	revocationServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		revoked = append(revoked, r.PostForm.Get("token"))
+		if r.PostForm.Get("token") == "targetRefreshToken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer revocationServer.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testLinkedCloudID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl", RevocationUrl: revocationServer.URL},
+	}))
+	require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+		ID:          "testID",
+		UserID:      "user0",
+		TargetURL:   "testTargetURL",
+		TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken", RefreshToken: "targetRefreshToken"},
+		OriginCloud: store.OAuth{AccessToken: "originAccessToken", RefreshToken: "originRefreshToken"},
+	}))
+	originCloud := store.LinkedCloud{Endpoint: store.Endpoint{RevocationUrl: revocationServer.URL}}
+	subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, nil, 0, 0)
+	rh := NewRequestHandler(originCloud, "", subManager, nil, nil, nil, s, nil, newTestAuthorizer(jwks.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+
+	r := httptest.NewRequest(http.MethodDelete, uri.LinkedAccounts+"/testID", nil)
+	r.Header.Set("Authorization", "Bearer "+newTestToken(t, key, newTestUserClaims("user0", "")))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"targetRefreshToken", "originRefreshToken"}, revoked)
+
+	err := s.LoadLinkedAccounts(ctx, store.Query{ID: "testID"}, &LinkedAccountHandler{})
+	assert.Error(t, err)
+}
@@ -1,18 +1,78 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/go-ocf/openapi-connector/store"
 	"github.com/gorilla/mux"
 )
 
 func (rh *RequestHandler) deleteLinkedCloud(w http.ResponseWriter, r *http.Request) (int, error) {
 	linkedCloudID, _ := mux.Vars(r)[linkedCloudIdKey]
-	err := rh.store.RemoveLinkedCloud(r.Context(), linkedCloudID)
+	version, ok, err := parseIfMatch(r)
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
+	if ok {
+		l, statusCode, err := rh.loadLinkedCloud(r.Context(), linkedCloudID)
+		if err != nil {
+			return statusCode, err
+		}
+		if l.Version != version {
+			return http.StatusPreconditionFailed, store.ConflictError{Kind: "linked cloud", ID: linkedCloudID}
+		}
+	}
+	cascade := false
+	if v := r.FormValue("cascade"); v != "" {
+		cascade, err = strconv.ParseBool(v)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("invalid cascade %v: %v", v, err)
+		}
+	}
+	statusCode, err := rh.removeDependentLinkedAccounts(r.Context(), linkedCloudID, cascade)
+	if err != nil {
+		return statusCode, err
+	}
+	err = rh.store.RemoveLinkedCloud(r.Context(), linkedCloudID)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	store.CloseHTTPClient(linkedCloudID)
+	return http.StatusOK, nil
+}
+
+// removeDependentLinkedAccounts removes linked accounts of the linked cloud when cascade is set,
+// otherwise the linked cloud cannot be deleted while a linked account uses it.
+func (rh *RequestHandler) removeDependentLinkedAccounts(ctx context.Context, linkedCloudID string, cascade bool) (int, error) {
+	var h LinkedAccountsHandler
+	err := rh.store.LoadLinkedAccounts(ctx, store.Query{LinkedCloudID: linkedCloudID}, &h)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("cannot load linked accounts: %v", err)
+	}
+	if len(h.linkedAccounts) == 0 {
+		return http.StatusOK, nil
+	}
+	if !cascade {
+		ids := make([]string, 0, len(h.linkedAccounts))
+		for _, l := range h.linkedAccounts {
+			ids = append(ids, l.ID)
+		}
+		return http.StatusConflict, fmt.Errorf("linked cloud %v is used by linked accounts %v", linkedCloudID, strings.Join(ids, ", "))
+	}
+	var errors []error
+	for _, l := range h.linkedAccounts {
+		err := rh.removeLinkedAccount(ctx, l)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("cannot remove linked account %v: %v", l.ID, err))
+		}
+	}
+	if len(errors) > 0 {
+		return http.StatusInternalServerError, fmt.Errorf("%v", errors)
+	}
 	return http.StatusOK, nil
 }
 
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteLinkedCloudDependants(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	admin := newTestToken(t, key, newTestUserClaims("admin", "testAdmin"))
+
+	tests := []struct {
+		name         string
+		id           string
+		query        string
+		wantCode     int
+		wantBody     string
+		wantAccounts []string
+		wantClouds   int
+	}{
+		{name: "used", id: "cloud0", wantCode: http.StatusConflict, wantBody: "linked accounts account0, account1", wantAccounts: []string{"account0", "account1", "account2"}, wantClouds: 3},
+		{name: "invalid cascade", id: "cloud0", query: "?cascade=invalid", wantCode: http.StatusBadRequest, wantAccounts: []string{"account0", "account1", "account2"}, wantClouds: 3},
+		{name: "without cascade", id: "cloud0", query: "?cascade=false", wantCode: http.StatusConflict, wantAccounts: []string{"account0", "account1", "account2"}, wantClouds: 3},
+		{name: "cascade", id: "cloud0", query: "?cascade=true", wantCode: http.StatusOK, wantAccounts: []string{"account2"}, wantClouds: 2},
+		{name: "unused", id: "cloud2", wantCode: http.StatusOK, wantAccounts: []string{"account0", "account1", "account2"}, wantClouds: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := inmemory.NewStore()
+			for _, id := range []string{"cloud0", "cloud1", "cloud2"} {
+				require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+					ID:           id,
+					ClientID:     "testClientID",
+					ClientSecret: "testClientSecret",
+					Scopes:       []string{"testScope"},
+					Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl"},
+				}))
+			}
+			for i, cloud := range []string{"cloud0", "cloud0", "cloud1"} {
+				require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+					ID:          fmt.Sprintf("account%v", i),
+					UserID:      "user0",
+					TargetURL:   "testTargetURL",
+					TargetCloud: store.OAuth{LinkedCloudID: cloud, AccessToken: "testAccessToken"},
+				}))
+			}
+			subManager := NewSubscriptionManager("", nil, nil, s, nil, store.LinkedCloud{}, nil, 0, 0)
+			rh := NewRequestHandler(store.LinkedCloud{}, "", subManager, nil, nil, nil, s, nil, newTestAuthorizer(jwks.URL), nil, nil)
+			h := NewHTTP(rh).Handler
+
+			r := httptest.NewRequest(http.MethodDelete, uri.LinkedClouds+"/"+tt.id+tt.query, nil)
+			r.Header.Set("Authorization", "Bearer "+admin)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+
+			var lh LinkedAccountsHandler
+			require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{}, &lh))
+			var accounts []string
+			for _, l := range lh.linkedAccounts {
+				accounts = append(accounts, l.ID)
+			}
+			assert.ElementsMatch(t, tt.wantAccounts, accounts)
+			var ch store.LinkedCloudsHandler
+			require.NoError(t, s.LoadLinkedClouds(ctx, store.Query{}, &ch))
+			assert.Len(t, ch.LinkedClouds, tt.wantClouds)
+		})
+	}
+}
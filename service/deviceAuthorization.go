@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
+)
+
+// startDeviceAuthorization starts the device authorization grant of the current step at the linked cloud of the step.
+func (rh *RequestHandler) startDeviceAuthorization(ctx context.Context, data LinkedAccountData) (LinkedAccountData, int, error) {
+	linkedCloud, err := rh.GetLinkedCloud(ctx, data)
+	if err != nil {
+		return data, http.StatusInternalServerError, err
+	}
+	d, err := linkedCloud.AuthorizeDevice(ctx)
+	if err != nil {
+		return data, http.StatusInternalServerError, err
+	}
+	now := time.Now()
+	data.DeviceAuthorization = d
+	data.DeviceExpiry = time.Time{}
+	if d.ExpiresIn > 0 {
+		data.DeviceExpiry = now.Add(time.Duration(d.ExpiresIn) * time.Second)
+	}
+	data.NextPollAt = now.Add(time.Duration(d.Interval) * time.Second)
+	return data, http.StatusOK, nil
+}
+
+func isDeviceCodeExpired(data LinkedAccountData, now time.Time) bool {
+	return !data.DeviceExpiry.IsZero() && now.After(data.DeviceExpiry)
+}
+
+// pollDeviceAuthorization polls the token endpoint of the current step unless the polling interval hasn't elapsed yet.
+// When the origin cloud step is finished, the device authorization of the target cloud is started. When the target cloud
+// step is finished, the linked account is stored and its ID is returned.
+func (rh *RequestHandler) pollDeviceAuthorization(ctx context.Context, data LinkedAccountData) (LinkedAccountData, string, int, error) {
+	now := time.Now()
+	if isDeviceCodeExpired(data, now) {
+		return data, "", http.StatusBadRequest, fmt.Errorf("device code is expired")
+	}
+	if now.Before(data.NextPollAt) {
+		return data, "", http.StatusOK, nil
+	}
+	linkedCloud, err := rh.GetLinkedCloud(ctx, data)
+	if err != nil {
+		return data, "", http.StatusInternalServerError, err
+	}
+	token, err := linkedCloud.DeviceAccessToken(ctx, data.DeviceAuthorization.DeviceCode)
+	switch {
+	case store.IsAuthorizationPending(err):
+		data.NextPollAt = now.Add(time.Duration(data.DeviceAuthorization.Interval) * time.Second)
+		return data, "", http.StatusOK, nil
+	case store.IsSlowDown(err):
+		data.DeviceAuthorization.Interval += 5
+		data.NextPollAt = now.Add(time.Duration(data.DeviceAuthorization.Interval) * time.Second)
+		return data, "", http.StatusOK, nil
+	case err != nil:
+		return data, "", http.StatusBadRequest, err
+	}
+	data, err = rh.provisionToken(data, &oauth2.Token{
+		AccessToken:  string(token.AccessToken),
+		RefreshToken: token.RefreshToken,
+		Expiry:       token.Expiry,
+	})
+	if err != nil {
+		return data, "", http.StatusBadRequest, err
+	}
+	if data.State == LinkedAccountState_PROVISIONED_ORIGIN_CLOUD {
+		var statusCode int
+		data, statusCode, err = rh.startDeviceAuthorization(ctx, data)
+		return data, "", statusCode, err
+	}
+	l, statusCode, err := rh.insertLinkedAccount(ctx, data.LinkedAccount)
+	return data, l.ID, statusCode, err
+}
+
+// addDeviceLinkedAccount starts the account linking by the device authorization grant for users who cannot be redirected
+// to the OAuth callback. The caller polls the returned device authorization until the linked account is added.
+func (rh *RequestHandler) addDeviceLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+	l, err := parseNewLinkedAccount(r)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	targetCloud, statusCode, err := rh.loadLinkedCloud(r.Context(), l.TargetCloud.LinkedCloudID)
+	if err != nil {
+		if statusCode == http.StatusNotFound {
+			statusCode = http.StatusBadRequest
+		}
+		return statusCode, err
+	}
+	for _, c := range []store.LinkedCloud{rh.originCloud, targetCloud} {
+		if c.Endpoint.DeviceAuthorizationUrl == "" {
+			return http.StatusBadRequest, fmt.Errorf("linked cloud %v doesn't support the device authorization grant", c.ID)
+		}
+	}
+
+	data, statusCode, err := rh.startDeviceAuthorization(r.Context(), LinkedAccountData{LinkedAccount: l})
+	if err != nil {
+		return statusCode, err
+	}
+	id, err := generateRandomString(32)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("cannot generate device authorization id")
+	}
+	err = rh.pendingLinks.Add(r.Context(), id, data)
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("cannot store pending link: %v", err)
+	}
+	err = writeJson(w, makeDeviceAuthorizationResponse(id, data, ""))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func (rh *RequestHandler) AddDeviceLinkedAccount(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.addDeviceLinkedAccount(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot add linked account: %v", err), statusCode, w)
+	}
+}
+
+// pollDeviceLinkedAccount advances the account linking by the device authorization grant. The pending link is taken
+// for the time of the poll, so concurrent polls of the same device authorization don't use the device code twice.
+// When the poll fails, the pending link is put back unless the authorization was denied or the device code expired.
+func (rh *RequestHandler) pollDeviceLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+	id, _ := mux.Vars(r)[deviceAuthorizationIdKey]
+	pending, err := rh.pendingLinks.Pop(r.Context(), id)
+	if err != nil || pending.DeviceAuthorization.DeviceCode == "" {
+		return http.StatusNotFound, fmt.Errorf("invalid/expired device authorization")
+	}
+	data, linkedAccountID, statusCode, err := rh.pollDeviceAuthorization(r.Context(), pending)
+	if err != nil {
+		now := time.Now()
+		if store.IsDeviceAuthorizationTerminated(err) || isDeviceCodeExpired(pending, now) {
+			return statusCode, err
+		}
+		// the poll is retried with the same device code after the interval
+		pending.NextPollAt = now.Add(time.Duration(pending.DeviceAuthorization.Interval) * time.Second)
+		aerr := rh.pendingLinks.Add(r.Context(), id, pending)
+		if aerr != nil {
+			return http.StatusInternalServerError, fmt.Errorf("%v; cannot store pending link: %v", err, aerr)
+		}
+		return statusCode, err
+	}
+	if linkedAccountID == "" {
+		err = rh.pendingLinks.Add(r.Context(), id, data)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("cannot store pending link: %v", err)
+		}
+	}
+	err = writeJson(w, makeDeviceAuthorizationResponse(id, data, linkedAccountID))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func (rh *RequestHandler) PollDeviceLinkedAccount(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.pollDeviceLinkedAccount(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot poll device authorization: %v", err), statusCode, w)
+	}
+}
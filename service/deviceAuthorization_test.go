@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceLinkedAccount(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	originAccessToken := newTestToken(t, key, newTestUserClaims("user0", ""))
+	var lock sync.Mutex
+	polls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/origin/device", "/target/device":
+			side := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/device")
+			fmt.Fprintf(w, `{"device_code":"%vDeviceCode","user_code":"%vUserCode","verification_uri":"https://%v/device","expires_in":600,"interval":1}`, side, side, side)
+		case "/token":
+			lock.Lock()
+			defer lock.Unlock()
+			deviceCode := r.PostForm.Get("device_code")
+			polls[deviceCode]++
+			switch {
+			case r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code":
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"unsupported_grant_type"}`))
+			case deviceCode == "originDeviceCode" && polls[deviceCode] == 1:
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"error":"authorization_pending"}`))
+			case deviceCode == "originDeviceCode":
+				fmt.Fprintf(w, `{"access_token":"%v","refresh_token":"originRefreshToken","token_type":"bearer","expires_in":3600}`, originAccessToken)
+			default:
+				w.Write([]byte(`{"access_token":"targetAccessToken","refresh_token":"targetRefreshToken","token_type":"bearer","expires_in":3600}`))
+			}
+		case "/devices/subscriptions":
+			w.Write([]byte(`{"subscriptionId":"testSubscriptionID"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	newCloud := func(id, deviceAuthorizationURL string) store.LinkedCloud {
+		return store.LinkedCloud{
+			ID:           id,
+			ClientID:     "testClientID",
+			ClientSecret: "testClientSecret",
+			Scopes:       []string{"testScope"},
+			Endpoint: store.Endpoint{
+				AuthUrl:                server.URL + "/authorize",
+				TokenUrl:               server.URL + "/token",
+				DeviceAuthorizationUrl: deviceAuthorizationURL,
+			},
+		}
+	}
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, newCloud("target", server.URL+"/target/device")))
+	require.NoError(t, s.InsertLinkedCloud(ctx, newCloud("withoutDevice", "")))
+	originCloud := newCloud("origin", server.URL+"/origin/device")
+	validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+	subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, validator, 0, 0)
+	pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+	rh := NewRequestHandler(originCloud, "https://connector/callback", subManager, nil, nil, nil, s, pendingLinks, newTestAuthorizer(jwks.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+
+	add := func(linkedCloudID string) *httptest.ResponseRecorder {
+		form := url.Values{}
+		form.Set("target_url", server.URL)
+		form.Set("target_linked_cloud_id", linkedCloudID)
+		r := httptest.NewRequest(http.MethodPost, uri.DeviceLinkedAccounts, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	poll := func(id string) (DeviceAuthorizationResponse, int) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri.DeviceLinkedAccounts+"/"+id, nil))
+		var resp DeviceAuthorizationResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return resp, w.Code
+	}
+
+	w := add("withoutDevice")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	_, code := poll("unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	w = add("target")
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DeviceAuthorizationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, LinkedAccountSide_ORIGIN, resp.Side)
+	assert.Equal(t, "originUserCode", resp.UserCode)
+	assert.Equal(t, "https://origin/device", resp.VerificationURI)
+
+	// a poll before the interval elapsed doesn't reach the token endpoint
+	resp, code = poll(resp.ID)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, LinkedAccountSide_ORIGIN, resp.Side)
+
+	sides := []LinkedAccountSide{}
+	for i := 0; i < 5 && resp.LinkedAccountID == ""; i++ {
+		time.Sleep(time.Duration(resp.Interval) * time.Second)
+		resp, code = poll(resp.ID)
+		require.Equal(t, http.StatusOK, code)
+		sides = append(sides, resp.Side)
+	}
+	require.NotEmpty(t, resp.LinkedAccountID)
+	assert.Equal(t, []LinkedAccountSide{LinkedAccountSide_ORIGIN, LinkedAccountSide_TARGET, ""}, sides)
+	assert.Equal(t, map[string]int{"originDeviceCode": 2, "targetDeviceCode": 1}, polls)
+
+	var lh LinkedAccountHandler
+	require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{ID: resp.LinkedAccountID}, &lh))
+	assert.Equal(t, "user0", lh.linkedAccount.UserID)
+	assert.Equal(t, store.AccessToken(originAccessToken), lh.linkedAccount.OriginCloud.AccessToken)
+	assert.Equal(t, "originRefreshToken", lh.linkedAccount.OriginCloud.RefreshToken)
+	assert.Equal(t, store.AccessToken("targetAccessToken"), lh.linkedAccount.TargetCloud.AccessToken)
+	assert.Equal(t, "target", lh.linkedAccount.TargetCloud.LinkedCloudID)
+
+	// the finished device authorization cannot be polled anymore
+	_, code = poll(resp.ID)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestDeviceLinkedAccountPollError(t *testing.T) {
+	jwks, _ := newTestJwks(t)
+	defer jwks.Close()
+	var lock sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/origin/device":
+			w.Write([]byte(`{"device_code":"originDeviceCode","user_code":"originUserCode","verification_uri":"https://origin/device","expires_in":600,"interval":1}`))
+		case "/token":
+			lock.Lock()
+			defer lock.Unlock()
+			polls++
+			if polls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"access_denied"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	target := store.LinkedCloud{
+		ID:           "target",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: server.URL + "/authorize", TokenUrl: server.URL + "/token", DeviceAuthorizationUrl: server.URL + "/target/device"},
+	}
+	require.NoError(t, s.InsertLinkedCloud(ctx, target))
+	originCloud := target
+	originCloud.ID = "origin"
+	originCloud.Endpoint.DeviceAuthorizationUrl = server.URL + "/origin/device"
+	validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+	subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, validator, 0, 0)
+	pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+	rh := NewRequestHandler(originCloud, "https://connector/callback", subManager, nil, nil, nil, s, pendingLinks, newTestAuthorizer(jwks.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+
+	form := url.Values{}
+	form.Set("target_url", server.URL)
+	form.Set("target_linked_cloud_id", "target")
+	r := httptest.NewRequest(http.MethodPost, uri.DeviceLinkedAccounts, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp DeviceAuthorizationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	poll := func() int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri.DeviceLinkedAccounts+"/"+resp.ID, nil))
+		return w.Code
+	}
+
+	// the unavailable token endpoint is polled again after the interval
+	time.Sleep(time.Second)
+	assert.Equal(t, http.StatusBadRequest, poll())
+	assert.Equal(t, http.StatusOK, poll())
+	time.Sleep(time.Second)
+	// the denied authorization cannot be polled anymore
+	assert.Equal(t, http.StatusBadRequest, poll())
+	assert.Equal(t, http.StatusNotFound, poll())
+	assert.Equal(t, 2, polls)
+}
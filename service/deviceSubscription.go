@@ -18,7 +18,11 @@ import (
 )
 
 func (s *SubscribeManager) subscribeToDevice(ctx context.Context, l store.LinkedAccount, correlationID, signingSecret, deviceID string) (string, error) {
-	resp, err := subscribe(ctx, "/devices/"+deviceID+"/subscriptions", correlationID, events.SubscriptionRequest{
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return "", err
+	}
+	resp, err := subscribe(ctx, client, "/devices/"+deviceID+"/subscriptions", correlationID, events.SubscriptionRequest{
 		URL: s.eventsURL,
 		EventType: []events.EventType{
 			events.EventType_ResourcesPublished,
@@ -32,8 +36,12 @@ func (s *SubscribeManager) subscribeToDevice(ctx context.Context, l store.Linked
 	return resp.SubscriptionId, nil
 }
 
-func cancelDeviceSubscription(ctx context.Context, l store.LinkedAccount, deviceID, subscriptionID string) error {
-	err := cancelSubscription(ctx, "/devices/"+deviceID+"/subscriptions/"+subscriptionID, l)
+func (s *SubscribeManager) cancelDeviceSubscription(ctx context.Context, l store.LinkedAccount, deviceID, subscriptionID string) error {
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	err = cancelSubscription(ctx, client, "/devices/"+deviceID+"/subscriptions/"+subscriptionID, l)
 	if err != nil {
 		return fmt.Errorf("cannot cancel device subscription for %v: %v", l.ID, err)
 	}
@@ -71,10 +79,7 @@ func (s *SubscribeManager) updateCloudStatus(ctx context.Context, deviceID strin
 
 // HandleResourcesPublished publish resources to resource aggregate and subscribes to resources.
 func (s *SubscribeManager) HandleResourcesPublished(ctx context.Context, d subscriptionData, header events.EventHeader, links events.ResourcesPublished) error {
-	userID, err := d.linkedAccount.OriginCloud.AccessToken.GetSubject()
-	if err != nil {
-		return fmt.Errorf("cannot get userID: %v", err)
-	}
+	userID := d.userID
 	var errors []error
 	for _, link := range links {
 		endpoints := make([]*pbRA.EndpointInformation, 0, 4)
@@ -148,7 +153,7 @@ func (s *SubscribeManager) HandleResourcesPublished(ctx context.Context, d subsc
 		}
 		_, err = s.store.FindOrCreateSubscription(ctx, sub)
 		if err != nil {
-			cancelResourceSubscription(ctx, d.linkedAccount, sub.DeviceID, sub.Href, sub.SubscriptionID)
+			s.cancelResourceSubscription(ctx, d.linkedAccount, sub.DeviceID, sub.Href, sub.SubscriptionID)
 			errors = append(errors, fmt.Errorf("cannot store resource subscription to DB: %v", err))
 			continue
 		}
@@ -161,10 +166,7 @@ func (s *SubscribeManager) HandleResourcesPublished(ctx context.Context, d subsc
 
 // HandleResourcesUnpublished unpublish resources from resource aggregate and cancel resources subscriptions.
 func (s *SubscribeManager) HandleResourcesUnpublished(ctx context.Context, d subscriptionData, header events.EventHeader, links events.ResourcesUnpublished) error {
-	userID, err := d.linkedAccount.OriginCloud.AccessToken.GetSubject()
-	if err != nil {
-		return fmt.Errorf("cannot get userID: %v", err)
-	}
+	userID := d.userID
 	var errors []error
 	for _, link := range links {
 		_, err := s.raClient.UnpublishResource(ctx, &pbRA.UnpublishResourceRequest{
@@ -182,7 +184,7 @@ func (s *SubscribeManager) HandleResourcesUnpublished(ctx context.Context, d sub
 		if err != nil {
 			errors = append(errors, fmt.Errorf("cannot unpublish resource: %v", err))
 		}
-		err = cancelResourceSubscription(ctx, d.linkedAccount, link.DeviceID, link.Href, header.SubscriptionID)
+		err = s.cancelResourceSubscription(ctx, d.linkedAccount, link.DeviceID, link.Href, header.SubscriptionID)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("cannot unsubscribe to resource: %v", err))
 		}
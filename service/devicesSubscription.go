@@ -17,7 +17,11 @@ import (
 
 func (s *SubscribeManager) subscribeToDevices(ctx context.Context, l store.LinkedAccount, correlationID, signingSecret string) (string, error) {
 
-	resp, err := subscribe(ctx, "/devices/subscriptions", correlationID, events.SubscriptionRequest{
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return "", err
+	}
+	resp, err := subscribe(ctx, client, "/devices/subscriptions", correlationID, events.SubscriptionRequest{
 		URL: s.eventsURL,
 		EventType: []events.EventType{
 			events.EventType_DevicesRegistered, events.EventType_DevicesUnregistered,
@@ -31,8 +35,12 @@ func (s *SubscribeManager) subscribeToDevices(ctx context.Context, l store.Linke
 	return resp.SubscriptionId, nil
 }
 
-func cancelDevicesSubscription(ctx context.Context, l store.LinkedAccount, subscriptionID string) error {
-	err := cancelSubscription(ctx, "/devices/subscriptions/"+subscriptionID, l)
+func (s *SubscribeManager) cancelDevicesSubscription(ctx context.Context, l store.LinkedAccount, subscriptionID string) error {
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	err = cancelSubscription(ctx, client, "/devices/subscriptions/"+subscriptionID, l)
 	if err != nil {
 		return fmt.Errorf("cannot cancel devices subscription for %v: %v", l.ID, err)
 	}
@@ -71,10 +79,7 @@ func (s *SubscribeManager) publishCloudDeviceStatus(ctx context.Context, deviceI
 
 func (s *SubscribeManager) HandleDevicesRegistered(ctx context.Context, d subscriptionData, devices events.DevicesRegistered, header events.EventHeader) error {
 	var errors []error
-	userID, err := d.linkedAccount.OriginCloud.AccessToken.GetSubject()
-	if err != nil {
-		return fmt.Errorf("cannot get userID: %v", err)
-	}
+	userID := d.userID
 	for _, device := range devices {
 		_, err := s.asClient.AddDevice(ctx, &pbAS.AddDeviceRequest{
 			DeviceId:    device.ID,
@@ -127,7 +132,7 @@ func (s *SubscribeManager) HandleDevicesRegistered(ctx context.Context, d subscr
 		}
 		_, err = s.store.FindOrCreateSubscription(ctx, sub)
 		if err != nil {
-			cancelDevicesSubscription(ctx, d.linkedAccount, sub.SubscriptionID)
+			s.cancelDevicesSubscription(ctx, d.linkedAccount, sub.SubscriptionID)
 			errors = append(errors, fmt.Errorf("cannot store subscription to DB: %v", err))
 			continue
 		}
@@ -148,13 +153,10 @@ func (s *SubscribeManager) HandleDevicesRegistered(ctx context.Context, d subscr
 }
 
 func (s *SubscribeManager) HandleDevicesUnregistered(ctx context.Context, subscriptionData subscriptionData, correlationID string, devices events.DevicesUnregistered) error {
-	userID, err := subscriptionData.linkedAccount.OriginCloud.AccessToken.GetSubject()
-	if err != nil {
-		return fmt.Errorf("cannot get userID: %v", err)
-	}
+	userID := subscriptionData.userID
 	var errors []error
 	for _, device := range devices {
-		err := cancelDeviceSubscription(ctx, subscriptionData.linkedAccount, device.ID, subscriptionData.subscription.SubscriptionID)
+		err := s.cancelDeviceSubscription(ctx, subscriptionData.linkedAccount, device.ID, subscriptionData.subscription.SubscriptionID)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("cannot cancel subscription to device %v: %v", device.ID, err))
 		}
@@ -188,18 +190,13 @@ func (s *SubscribeManager) HandleDevicesUnregistered(ctx context.Context, subscr
 func (s *SubscribeManager) HandleDevicesOnline(ctx context.Context, subscriptionData subscriptionData, header events.EventHeader, devices events.DevicesOnline) error {
 	var errors []error
 	for _, device := range devices {
-		userID, err := subscriptionData.linkedAccount.OriginCloud.AccessToken.GetSubject()
-		if err != nil {
-			errors = append(errors, fmt.Errorf("cannot get userID for set device(%v) online: %v", device.ID, err))
-			continue
-		}
 		authCtx := pbCQRS.AuthorizationContext{
-			UserId:      userID,
+			UserId:      subscriptionData.userID,
 			AccessToken: string(subscriptionData.linkedAccount.OriginCloud.AccessToken),
 			DeviceId:    device.ID,
 		}
 
-		err = s.updateCloudStatus(ctx, device.ID, true, authCtx, header.SequenceNumber)
+		err := s.updateCloudStatus(ctx, device.ID, true, authCtx, header.SequenceNumber)
 
 		if err != nil {
 			errors = append(errors, fmt.Errorf("cannot set device %v to online: %v", device.ID, err))
@@ -216,18 +213,13 @@ func (s *SubscribeManager) HandleDevicesOnline(ctx context.Context, subscription
 func (s *SubscribeManager) HandleDevicesOffline(ctx context.Context, subscriptionData subscriptionData, header events.EventHeader, devices events.DevicesOffline) error {
 	var errors []error
 	for _, device := range devices {
-		userID, err := subscriptionData.linkedAccount.OriginCloud.AccessToken.GetSubject()
-		if err != nil {
-			errors = append(errors, fmt.Errorf("cannot get userID for set device(%v) offline: %v", device.ID, err))
-			continue
-		}
 		authCtx := pbCQRS.AuthorizationContext{
-			UserId:      userID,
+			UserId:      subscriptionData.userID,
 			AccessToken: string(subscriptionData.linkedAccount.OriginCloud.AccessToken),
 			DeviceId:    device.ID,
 		}
 
-		err = s.updateCloudStatus(ctx, device.ID, false, authCtx, header.SequenceNumber)
+		err := s.updateCloudStatus(ctx, device.ID, false, authCtx, header.SequenceNumber)
 
 		if err != nil {
 			errors = append(errors, fmt.Errorf("cannot set device %v to offline: %v", device.ID, err))
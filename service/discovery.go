@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/patrickmn/go-cache"
+)
+
+// DiscoveryConfig configures the OpenID discovery of linked clouds defined by an issuer.
+type DiscoveryConfig struct {
+	// RefreshInterval openid-configurations are cached for the interval and endpoints of stored linked clouds are refreshed by it.
+	RefreshInterval time.Duration `envconfig:"DISCOVERY_REFRESH_INTERVAL" default:"1h"`
+}
+
+// Discovery populates endpoints of linked clouds from openid-configurations of their issuers.
+type Discovery struct {
+	cfg   DiscoveryConfig
+	store store.Store
+	cache *cache.Cache
+}
+
+// NewDiscovery creates a new Discovery.
+func NewDiscovery(cfg DiscoveryConfig, s store.Store) *Discovery {
+	return &Discovery{
+		cfg:   cfg,
+		store: s,
+		cache: cache.New(cfg.RefreshInterval, cfg.RefreshInterval),
+	}
+}
+
+func (d *Discovery) getOpenIDConfiguration(ctx context.Context, l store.LinkedCloud) (store.OpenIDConfiguration, error) {
+	if c, ok := d.cache.Get(l.Issuer); ok {
+		return c.(store.OpenIDConfiguration), nil
+	}
+	client, err := l.HTTPClient()
+	if err != nil {
+		return store.OpenIDConfiguration{}, err
+	}
+	c, err := store.GetOpenIDConfiguration(ctx, client, l.Issuer)
+	if err != nil {
+		return c, err
+	}
+	d.cache.Set(l.Issuer, c, cache.DefaultExpiration)
+	return c, nil
+}
+
+// Resolve returns the linked cloud with discovered endpoints. The linked cloud without issuer is returned as it is.
+func (d *Discovery) Resolve(ctx context.Context, l store.LinkedCloud) (store.LinkedCloud, error) {
+	if l.Issuer == "" {
+		return l, nil
+	}
+	c, err := d.getOpenIDConfiguration(ctx, l)
+	if err != nil {
+		return l, fmt.Errorf("cannot discover endpoints of linked cloud: %v", err)
+	}
+	return l.WithOpenIDConfiguration(c), nil
+}
+
+// Run refreshes endpoints of stored linked clouds every interval until the ctx is done.
+func (d *Discovery) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		d.RefreshLinkedClouds(ctx)
+	}
+}
+
+// RefreshLinkedClouds updates stored linked clouds whose discovered endpoints were changed. Failures are logged,
+// the linked cloud keeps its endpoints.
+func (d *Discovery) RefreshLinkedClouds(ctx context.Context) {
+	var h store.LinkedCloudsHandler
+	err := d.store.LoadLinkedClouds(ctx, store.Query{}, &h)
+	if err != nil {
+		log.Errorf("cannot load linked clouds to refresh endpoints: %v", err)
+		return
+	}
+	for _, l := range h.LinkedClouds {
+		if ctx.Err() != nil {
+			return
+		}
+		if l.Issuer == "" {
+			continue
+		}
+		resolved, err := d.Resolve(ctx, l)
+		if err != nil {
+			log.Errorf("cannot refresh endpoints of linked cloud %v: %v", l.ID, err)
+			continue
+		}
+		if resolved.Endpoint == l.Endpoint && resolved.JwksURL == l.JwksURL {
+			continue
+		}
+		// a concurrent update of the linked cloud is refreshed by the next run
+		err = d.store.UpdateLinkedCloud(ctx, resolved)
+		if err != nil {
+			log.Errorf("cannot refresh endpoints of linked cloud %v: %v", l.ID, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscovery(t *testing.T) {
+	var calls int32
+	tokenPath := "/token"
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"issuer":"%v","authorization_endpoint":"%v/authorize","token_endpoint":"%v%v","jwks_uri":"%v/jwks"}`,
+			server.URL, server.URL, server.URL, tokenPath, server.URL)
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	d := NewDiscovery(DiscoveryConfig{RefreshInterval: time.Hour}, s)
+	l := store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Issuer:       server.URL,
+	}
+
+	resolved, err := d.Resolve(ctx, l)
+	require.NoError(t, err)
+	assert.Equal(t, store.Endpoint{AuthUrl: server.URL + "/authorize", TokenUrl: server.URL + "/token"}, resolved.Endpoint)
+	assert.Equal(t, server.URL+"/jwks", resolved.JwksURL)
+	_, err = d.Resolve(ctx, l)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	require.NoError(t, s.InsertLinkedCloud(ctx, resolved))
+	d.RefreshLinkedClouds(ctx)
+	var h store.LinkedCloudsHandler
+	require.NoError(t, s.LoadLinkedClouds(ctx, store.Query{ID: l.ID}, &h))
+	require.Len(t, h.LinkedClouds, 1)
+	assert.Equal(t, uint64(0), h.LinkedClouds[0].Version)
+
+	// the openid-configuration is fetched again when the cached one expires
+	tokenPath = "/newToken"
+	d.cache.Flush()
+	d.RefreshLinkedClouds(ctx)
+	h = store.LinkedCloudsHandler{}
+	require.NoError(t, s.LoadLinkedClouds(ctx, store.Query{ID: l.ID}, &h))
+	require.Len(t, h.LinkedClouds, 1)
+	assert.Equal(t, server.URL+"/newToken", h.LinkedClouds[0].Endpoint.TokenUrl)
+	assert.Equal(t, uint64(1), h.LinkedClouds[0].Version)
+}
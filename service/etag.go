@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	ETagHeader    = "ETag"
+	IfMatchHeader = "If-Match"
+)
+
+// makeETag returns the strong entity tag of the version of a stored record.
+func makeETag(version uint64) string {
+	return strconv.Quote(strconv.FormatUint(version, 10))
+}
+
+// parseIfMatch returns the version required by the If-Match header. It returns false when
+// the header is missing or it is "*", so any version matches.
+func parseIfMatch(r *http.Request) (uint64, bool, error) {
+	v := strings.TrimSpace(r.Header.Get(IfMatchHeader))
+	if v == "" || v == "*" {
+		return 0, false, nil
+	}
+	s, err := strconv.Unquote(v)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %v %v: strong entity tag is expected", IfMatchHeader, v)
+	}
+	version, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %v %v: unknown entity tag", IfMatchHeader, v)
+	}
+	return version, true, nil
+}
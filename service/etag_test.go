@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    uint64
+		wantOk  bool
+		wantErr bool
+	}{
+		{name: "missing"},
+		{name: "any", ifMatch: "*"},
+		{name: "valid", ifMatch: `"3"`, want: 3, wantOk: true},
+		{name: "unquoted", ifMatch: "3", wantErr: true},
+		{name: "weak", ifMatch: `W/"3"`, wantErr: true},
+		{name: "unknown", ifMatch: `"abc"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set(IfMatchHeader, tt.ifMatch)
+			}
+			got, ok, err := parseIfMatch(r)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOk, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLinkedCloudETag(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testID",
+		Name:         "testName",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl"},
+	}))
+	rh := NewRequestHandler(store.LinkedCloud{}, "", nil, nil, nil, nil, s, nil, newTestAuthorizer(server.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+	admin := newTestToken(t, key, newTestUserClaims("admin", "testAdmin"))
+
+	do := func(method, path, ifMatch string, body []byte) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+admin)
+		if ifMatch != "" {
+			r.Header.Set(IfMatchHeader, ifMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	update, err := json.Encode(store.LinkedCloud{
+		ID:           "testID",
+		Name:         "testNameUpdated",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl"},
+	})
+	require.NoError(t, err)
+
+	w := do(http.MethodGet, uri.LinkedClouds+"/testID", "", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"0"`, w.Header().Get(ETagHeader))
+
+	w = do(http.MethodGet, uri.LinkedClouds+"/notFound", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = do(http.MethodPost, uri.LinkedClouds, `"0"`, update)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"1"`, w.Header().Get(ETagHeader))
+	var resp LinkedCloudResponse
+	require.NoError(t, json.Decode(w.Body.Bytes(), &resp))
+	assert.Equal(t, uint64(1), resp.Version)
+	assert.Equal(t, "testNameUpdated", resp.Name)
+
+	w = do(http.MethodPost, uri.LinkedClouds, `"0"`, update)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = do(http.MethodPost, uri.LinkedClouds, "", update)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get(ETagHeader))
+
+	w = do(http.MethodDelete, uri.LinkedClouds+"/testID", `"1"`, nil)
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	w = do(http.MethodDelete, uri.LinkedClouds+"/testID", `"2"`, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
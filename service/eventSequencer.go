@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// eventSequencerMetrics counts out-of-order events of all subscriptions. They are published by expvar as "eventSequencer":
+// bufferedEvents - events which waited for events with lower sequence numbers
+// gaps - sequence numbers which were skipped, because missing events didn't arrive in time
+// missedEvents - events which were skipped by gaps
+// staleEvents - events which arrived after an event with a higher sequence number was applied
+var eventSequencerMetrics = expvar.NewMap("eventSequencer")
+
+// sequencerIdleExpiration sequences of subscriptions without events are forgotten after it,
+// they are loaded from the store by the next event.
+const sequencerIdleExpiration = 10 * time.Minute
+
+type subscriptionSequence struct {
+	// next is the sequence number of the next applied event. It is unknown when no event of the subscription
+	// was applied yet, then the first events wait for the timeout and the lowest of them is applied first.
+	next    uint64
+	known   bool
+	busy    bool
+	waiting map[uint64]chan struct{}
+	idleAt  time.Time
+}
+
+// lowestWaiting returns the lowest sequence number of waiting events.
+func (q *subscriptionSequence) lowestWaiting() uint64 {
+	var lowest uint64
+	found := false
+	for seq := range q.waiting {
+		if !found || seq < lowest {
+			lowest = seq
+			found = true
+		}
+	}
+	return lowest
+}
+
+// eventSequencer applies events of each subscription one by one in the order of their sequence numbers.
+// An event which arrives before events with lower sequence numbers is buffered until they are applied
+// or until the timeout, then the missing events are skipped. Events are ordered within the process,
+// so events of a subscription are ordered only when they are delivered to the same instance.
+type eventSequencer struct {
+	store   store.Store
+	timeout time.Duration
+
+	lock      sync.Mutex
+	sequences map[string]*subscriptionSequence
+	sweptAt   time.Time
+}
+
+func newEventSequencer(s store.Store, timeout time.Duration) *eventSequencer {
+	return &eventSequencer{
+		store:     s,
+		timeout:   timeout,
+		sequences: make(map[string]*subscriptionSequence),
+		sweptAt:   time.Now(),
+	}
+}
+
+// getSequence returns the sequence of the subscription. A new sequence continues
+// from NextSequenceNumber of the stored subscription.
+func (e *eventSequencer) getSequence(ctx context.Context, subscriptionID string) (*subscriptionSequence, error) {
+	e.lock.Lock()
+	q, ok := e.sequences[subscriptionID]
+	e.lock.Unlock()
+	if ok {
+		return q, nil
+	}
+
+	var h SubscriptionHandler
+	err := e.store.LoadSubscriptions(ctx, []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: subscriptionID}}, &h)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load subscription from DB: %v", err)
+	}
+	if !h.ok {
+		return nil, fmt.Errorf("unknown subscription %v", subscriptionID)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	// the sequence could be created by a concurrent event meanwhile
+	if q, ok := e.sequences[subscriptionID]; ok {
+		return q, nil
+	}
+	q = &subscriptionSequence{
+		next:    h.subscription.NextSequenceNumber,
+		known:   h.subscription.NextSequenceNumber > 0,
+		waiting: make(map[uint64]chan struct{}),
+	}
+	e.sequences[subscriptionID] = q
+	return q, nil
+}
+
+// skipTo applies the event with the sequence number although events before it are missing. It must be called under the lock.
+func (e *eventSequencer) skipTo(subscriptionID string, q *subscriptionSequence, sequenceNumber uint64) {
+	missed := sequenceNumber - q.next
+	eventSequencerMetrics.Add("gaps", 1)
+	eventSequencerMetrics.Add("missedEvents", int64(missed))
+	log.Errorf("subscription %v: %v events from sequence number %v didn't arrive in %v, they are skipped", subscriptionID, missed, q.next, e.timeout)
+	q.next = sequenceNumber
+	q.busy = true
+	delete(q.waiting, sequenceNumber)
+}
+
+// startAt applies the event with the sequence number as the first event of the subscription. It must be called under the lock.
+func (e *eventSequencer) startAt(q *subscriptionSequence, sequenceNumber uint64) {
+	q.next = sequenceNumber
+	q.known = true
+	q.busy = true
+	delete(q.waiting, sequenceNumber)
+}
+
+// release finishes the event with the sequence number and passes the turn to the next event. The turn stays
+// at the sequence number when the event wasn't applied, so it can be delivered again.
+func (e *eventSequencer) release(q *subscriptionSequence, sequenceNumber uint64, applied bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	q.busy = false
+	if !applied {
+		return
+	}
+	q.next = sequenceNumber + 1
+	if granted, ok := q.waiting[q.next]; ok {
+		delete(q.waiting, q.next)
+		q.busy = true
+		close(granted)
+		return
+	}
+	now := time.Now()
+	if len(q.waiting) == 0 {
+		q.idleAt = now
+	}
+	if now.Sub(e.sweptAt) < sequencerIdleExpiration {
+		return
+	}
+	e.sweptAt = now
+	for id, s := range e.sequences {
+		if !s.busy && len(s.waiting) == 0 && now.Sub(s.idleAt) > sequencerIdleExpiration {
+			delete(e.sequences, id)
+		}
+	}
+}
+
+// Wait blocks until the event with the sequence number is next in the order of the subscription. The caller applies
+// the event and calls the returned release function with the result. StaleSequenceNumberError is returned when an event with a higher
+// sequence number was already applied or when the event with the same sequence number is being applied or waits.
+func (e *eventSequencer) Wait(ctx context.Context, subscriptionID string, sequenceNumber uint64) (release func(applied bool), err error) {
+	q, err := e.getSequence(ctx, subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	release = func(applied bool) { e.release(q, sequenceNumber, applied) }
+	stale := store.StaleSequenceNumberError{SubscriptionID: subscriptionID, SequenceNumber: sequenceNumber}
+
+	e.lock.Lock()
+	if e.sequences[subscriptionID] != q {
+		// the idle sequence was forgotten meanwhile
+		e.lock.Unlock()
+		return e.Wait(ctx, subscriptionID, sequenceNumber)
+	}
+	if q.known && sequenceNumber < q.next {
+		e.lock.Unlock()
+		eventSequencerMetrics.Add("staleEvents", 1)
+		return nil, stale
+	}
+	if q.known && sequenceNumber == q.next && !q.busy {
+		q.busy = true
+		e.lock.Unlock()
+		return release, nil
+	}
+	if _, ok := q.waiting[sequenceNumber]; ok || (q.known && sequenceNumber == q.next) {
+		e.lock.Unlock()
+		return nil, stale
+	}
+	granted := make(chan struct{})
+	q.waiting[sequenceNumber] = granted
+	e.lock.Unlock()
+	eventSequencerMetrics.Add("bufferedEvents", 1)
+
+	timer := time.NewTimer(e.timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case <-granted:
+			return release, nil
+		case <-ctx.Done():
+			e.lock.Lock()
+			select {
+			case <-granted:
+				// the event wasn't applied, so the turn stays at its sequence number for a redelivery
+				q.busy = false
+			default:
+				delete(q.waiting, sequenceNumber)
+			}
+			e.lock.Unlock()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		e.lock.Lock()
+		select {
+		case <-granted:
+			e.lock.Unlock()
+			return release, nil
+		default:
+		}
+		if !q.busy {
+			lowest := q.lowestWaiting()
+			lowestGranted := q.waiting[lowest]
+			if q.known {
+				e.skipTo(subscriptionID, q, lowest)
+			} else {
+				e.startAt(q, lowest)
+			}
+			if lowest == sequenceNumber {
+				e.lock.Unlock()
+				return release, nil
+			}
+			// the event with the lowest sequence number arrived later, so its timeout didn't elapse yet
+			close(lowestGranted)
+		}
+		e.lock.Unlock()
+		timer.Reset(e.timeout)
+	}
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEventSequencer(t *testing.T, timeout time.Duration) *eventSequencer {
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	_, err := s.FindOrCreateSubscription(ctx, store.Subscription{
+		SubscriptionID:  "testSubscriptionID",
+		Type:            store.Type_Devices,
+		LinkedAccountID: "testLinkedAccountID",
+		SigningSecret:   "testSigningSecret",
+	})
+	require.NoError(t, err)
+	// the next event has sequence number 1
+	require.NoError(t, s.UpdateSubscriptionSequenceNumber(ctx, "testSubscriptionID", 0))
+	return newEventSequencer(s, timeout)
+}
+
+func eventSequencerMetric(key string) int64 {
+	v, ok := eventSequencerMetrics.Get(key).(*expvar.Int)
+	if !ok {
+		return 0
+	}
+	return v.Value()
+}
+
+func TestEventSequencerOrdersEvents(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEventSequencer(t, time.Minute)
+
+	applied := make(chan uint64, 2)
+	for _, seq := range []uint64{3, 2} {
+		go func(seq uint64) {
+			release, err := e.Wait(ctx, "testSubscriptionID", seq)
+			if !assert.NoError(t, err) {
+				return
+			}
+			applied <- seq
+			release(true)
+		}(seq)
+	}
+	select {
+	case seq := <-applied:
+		require.FailNow(t, "event applied before its turn", "sequence number %v", seq)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release, err := e.Wait(ctx, "testSubscriptionID", 1)
+	require.NoError(t, err)
+	release(true)
+	for _, want := range []uint64{2, 3} {
+		select {
+		case seq := <-applied:
+			assert.Equal(t, want, seq)
+		case <-time.After(time.Second):
+			require.FailNow(t, "event wasn't applied", "sequence number %v", want)
+		}
+	}
+
+	_, err = e.Wait(ctx, "testSubscriptionID", 2)
+	assert.True(t, store.IsStaleSequenceNumberError(err))
+}
+
+func TestEventSequencerSkipsGap(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEventSequencer(t, 100*time.Millisecond)
+	gaps := eventSequencerMetric("gaps")
+	missedEvents := eventSequencerMetric("missedEvents")
+
+	start := time.Now()
+	release, err := e.Wait(ctx, "testSubscriptionID", 3)
+	require.NoError(t, err)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	release(true)
+	assert.Equal(t, gaps+1, eventSequencerMetric("gaps"))
+	assert.Equal(t, missedEvents+2, eventSequencerMetric("missedEvents"))
+
+	// the missing event arrives too late
+	_, err = e.Wait(ctx, "testSubscriptionID", 2)
+	assert.True(t, store.IsStaleSequenceNumberError(err))
+}
+
+func TestEventSequencerKeepsTurnOfNotAppliedEvent(t *testing.T) {
+	ctx := context.Background()
+	e := newTestEventSequencer(t, time.Minute)
+
+	release, err := e.Wait(ctx, "testSubscriptionID", 1)
+	require.NoError(t, err)
+	// the same event delivered concurrently
+	_, err = e.Wait(ctx, "testSubscriptionID", 1)
+	assert.True(t, store.IsStaleSequenceNumberError(err))
+	release(false)
+
+	release, err = e.Wait(ctx, "testSubscriptionID", 1)
+	require.NoError(t, err)
+	release(true)
+}
+
+func TestEventSequencerCanceledWait(t *testing.T) {
+	e := newTestEventSequencer(t, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := e.Wait(ctx, "testSubscriptionID", 2)
+	require.Error(t, err)
+
+	// the canceled event can be delivered again
+	ctx = context.Background()
+	release, err := e.Wait(ctx, "testSubscriptionID", 1)
+	require.NoError(t, err)
+	release(true)
+	release, err = e.Wait(ctx, "testSubscriptionID", 2)
+	require.NoError(t, err)
+	release(true)
+}
+
+func TestEventSequencerStartsAtLowestFirstEvent(t *testing.T) {
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	_, err := s.FindOrCreateSubscription(ctx, store.Subscription{
+		SubscriptionID:  "testSubscriptionID",
+		Type:            store.Type_Devices,
+		LinkedAccountID: "testLinkedAccountID",
+		SigningSecret:   "testSigningSecret",
+	})
+	require.NoError(t, err)
+	// no event of the subscription was applied yet
+	e := newEventSequencer(s, 100*time.Millisecond)
+	gaps := eventSequencerMetric("gaps")
+
+	applied := make(chan uint64, 2)
+	go func() {
+		release, err := e.Wait(ctx, "testSubscriptionID", 1)
+		if assert.NoError(t, err) {
+			applied <- 1
+			release(true)
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+	release, err := e.Wait(ctx, "testSubscriptionID", 0)
+	require.NoError(t, err)
+	applied <- 0
+	release(true)
+
+	assert.Equal(t, uint64(0), <-applied)
+	assert.Equal(t, uint64(1), <-applied)
+	assert.Equal(t, gaps, eventSequencerMetric("gaps"))
+	_, err = e.Wait(ctx, "testSubscriptionID", 0)
+	assert.True(t, store.IsStaleSequenceNumberError(err))
+}
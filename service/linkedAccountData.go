@@ -1,13 +1,37 @@
 package service
 
-import "github.com/go-ocf/openapi-connector/store"
+import (
+	"time"
 
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// LinkedAccountData is kept by PendingLinkStore during account linking.
 type LinkedAccountData struct {
-	originCloud   store.LinkedCloud
 	LinkedAccount store.LinkedAccount
 	State         LinkedAccountState
+	// CodeVerifier of PKCE for the current step. It is empty when the linked cloud of the step doesn't use PKCE.
+	CodeVerifier string
+	// Reauthorize is set when tokens of the existing linked account LinkedAccount.ID are replaced instead of adding a new linked account.
+	Reauthorize LinkedAccountSide
+	// RedirectURI of the caller which receives the result of the linking. The caller isn't redirected when it is empty.
+	RedirectURI string
+	// DeviceAuthorization of the current step when the user is authorized by the device authorization grant (RFC 8628).
+	DeviceAuthorization store.DeviceAuthorization
+	// DeviceExpiry is the expiration of the device code of the current step. The device code doesn't expire when it is zero.
+	DeviceExpiry time.Time
+	// NextPollAt is the earliest time when the token endpoint is polled for the device code again.
+	NextPollAt time.Time
 }
 
+// LinkedAccountSide selects the cloud of a linked account.
+type LinkedAccountSide string
+
+const (
+	LinkedAccountSide_ORIGIN LinkedAccountSide = "origin"
+	LinkedAccountSide_TARGET LinkedAccountSide = "target"
+)
+
 type LinkedAccountState uint8
 
 const (
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// backfillLinkedAccountOwner sets UserID of the linked account stored before owners were persisted. The owner is the subject
+// of the origin cloud access token, which is refreshed when it is expired.
+func backfillLinkedAccountOwner(ctx context.Context, s store.Store, originCloud store.LinkedCloud, validator TokenValidator, l store.LinkedAccount) error {
+	l, err := l.RefreshTokens(ctx, s, originCloud)
+	if err != nil {
+		return err
+	}
+	userID, err := verifyOriginToken(validator, l.OriginCloud.AccessToken)
+	if err != nil {
+		return fmt.Errorf("cannot verify origin cloud access token: %v", err)
+	}
+	l.UserID = userID
+	return s.UpdateLinkedAccount(ctx, l)
+}
+
+// BackfillLinkedAccountOwners sets UserID of linked accounts stored before owners were persisted, so their owners can
+// retrieve and delete them again. Until then, such linked accounts are visible only to admins. The service runs it at start,
+// linked accounts which fail are logged and retried by the next start.
+func BackfillLinkedAccountOwners(ctx context.Context, s store.Store, originCloud store.LinkedCloud, validator TokenValidator) {
+	var h LinkedAccountsHandler
+	err := s.LoadLinkedAccounts(ctx, store.Query{}, &h)
+	if err != nil {
+		log.Errorf("cannot load linked accounts to backfill owners: %v", err)
+		return
+	}
+	for _, l := range h.linkedAccounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if l.UserID != "" || l.IsReauthRequired() || l.IsDisabled() {
+			continue
+		}
+		err := backfillLinkedAccountOwner(ctx, s, originCloud, validator, l)
+		if err != nil {
+			log.Errorf("cannot backfill owner of linked account %v: %v", l.ID, err)
+		}
+	}
+}
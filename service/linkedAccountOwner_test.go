@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillLinkedAccountOwners(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	valid := store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, newTestUserClaims("user0", ""))), Expiry: time.Now().Add(time.Hour)}
+	accounts := []store.LinkedAccount{
+		{ID: "withoutOwner", TargetURL: "testTargetURL", TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken"}, OriginCloud: valid},
+		{ID: "withOwner", UserID: "user1", TargetURL: "testTargetURL", TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken"}, OriginCloud: valid},
+		{ID: "invalidToken", TargetURL: "testTargetURL", TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken"}, OriginCloud: store.OAuth{AccessToken: "invalid"}},
+	}
+	for _, l := range accounts {
+		require.NoError(t, s.InsertLinkedAccount(ctx, l))
+	}
+
+	BackfillLinkedAccountOwners(ctx, s, store.LinkedCloud{}, validator)
+
+	var h LinkedAccountsHandler
+	require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{}, &h))
+	owners := make(map[string]string)
+	for _, l := range h.linkedAccounts {
+		owners[l.ID] = l.UserID
+	}
+	assert.Equal(t, map[string]string{"withoutOwner": "user0", "withOwner": "user1", "invalidToken": ""}, owners)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/gorilla/mux"
+)
+
+func recordLinkedAccountFailure(ctx context.Context, s store.Store, l store.LinkedAccount, failure error) {
+	err := store.RecordLinkedAccountFailure(ctx, s, l.ID, failure)
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+func recordLinkedAccountSuccess(ctx context.Context, s store.Store, l store.LinkedAccount) {
+	err := store.RecordLinkedAccountSuccess(ctx, s, l)
+	if err != nil {
+		log.Errorf("%v", err)
+	}
+}
+
+// LinkedAccountStatusRequest sets the status of a linked account. Only active and disabled can be set.
+type LinkedAccountStatusRequest struct {
+	Status store.LinkedAccountStatus `json:"Status"`
+}
+
+func (rh *RequestHandler) updateLinkedAccountStatus(w http.ResponseWriter, r *http.Request) (int, error) {
+	linkedAccountID, _ := mux.Vars(r)[linkedAccountIdKey]
+	buffer := bytes.NewBuffer(make([]byte, 0, 1024))
+	_, err := buffer.ReadFrom(r.Body)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("cannot read body: %v", err)
+	}
+	var req LinkedAccountStatusRequest
+	err = json.Decode(buffer.Bytes(), &req)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("cannot decode body: %v", err)
+	}
+	switch req.Status {
+	case store.LinkedAccountStatus_ACTIVE, store.LinkedAccountStatus_DISABLED:
+	default:
+		return http.StatusBadRequest, fmt.Errorf("invalid status %v", req.Status)
+	}
+	l, err := store.SetLinkedAccountStatus(r.Context(), rh.store, linkedAccountID, req.Status)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	err = writeJson(w, makeLinkedAccountResponse(l))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func (rh *RequestHandler) UpdateLinkedAccountStatus(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.updateLinkedAccountStatus(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot update linked account status: %v", err), statusCode, w)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateLinkedAccountStatus(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+		ID:          "testID",
+		UserID:      "user0",
+		TargetURL:   "testTargetURL",
+		TargetCloud: store.OAuth{LinkedCloudID: "testID", AccessToken: "testAccessToken"},
+	}))
+	rh := NewRequestHandler(store.LinkedCloud{}, "", nil, nil, nil, nil, s, nil, newTestAuthorizer(server.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+	admin := newTestToken(t, key, newTestUserClaims("admin", "testAdmin"))
+	user0 := newTestToken(t, key, newTestUserClaims("user0", ""))
+
+	tests := []struct {
+		name       string
+		token      string
+		id         string
+		body       string
+		wantCode   int
+		wantStatus store.LinkedAccountStatus
+	}{
+		{name: "not admin", token: user0, id: "testID", body: `{"Status":"disabled"}`, wantCode: http.StatusForbidden},
+		{name: "invalid status", token: admin, id: "testID", body: `{"Status":"degraded"}`, wantCode: http.StatusBadRequest},
+		{name: "not found", token: admin, id: "notFound", body: `{"Status":"disabled"}`, wantCode: http.StatusBadRequest},
+		{name: "disable", token: admin, id: "testID", body: `{"Status":"disabled"}`, wantCode: http.StatusOK, wantStatus: store.LinkedAccountStatus_DISABLED},
+		{name: "activate", token: admin, id: "testID", body: `{"Status":"active"}`, wantCode: http.StatusOK, wantStatus: store.LinkedAccountStatus_ACTIVE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, uri.LinkedAccounts+"/"+tt.id+"/status", strings.NewReader(tt.body))
+			r.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var resp LinkedAccountResponse
+			require.NoError(t, json.Decode(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantStatus, resp.Status)
+			assert.NotNil(t, resp.StatusChangedAt)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RetrieveMetrics writes metrics of the service in the expvar format. Other variables published by expvar,
+// e.g. the command line of the process, are not exposed.
+func (rh *RequestHandler) RetrieveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprintf(w, "{\n%q: %v\n}\n", "eventSequencer", eventSequencerMetrics.String())
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetrieveMetrics(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+	rh := NewRequestHandler(store.LinkedCloud{}, "", nil, nil, nil, nil, nil, nil, newTestAuthorizer(server.URL), nil, nil)
+	h := NewHTTP(rh).Handler
+	admin := newTestToken(t, key, newTestUserClaims("admin", "testAdmin"))
+	user0 := newTestToken(t, key, newTestUserClaims("user0", ""))
+
+	r := httptest.NewRequest(http.MethodGet, uri.Metrics, nil)
+	r.Header.Set("Authorization", "Bearer "+user0)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	r = httptest.NewRequest(http.MethodGet, uri.Metrics, nil)
+	r.Header.Set("Authorization", "Bearer "+admin)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	var metrics map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &metrics))
+	assert.Len(t, metrics, 1)
+	assert.Contains(t, metrics, "eventSequencer")
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 
+	"github.com/go-ocf/kit/log"
 	"github.com/gofrs/uuid"
 
 	"github.com/go-ocf/openapi-connector/store"
@@ -15,79 +17,161 @@ func (rh *RequestHandler) HandleLinkedAccount(ctx context.Context, data LinkedAc
 	var oauth oauth2.Config
 	switch data.State {
 	case LinkedAccountState_START:
+		client, err := rh.originCloud.HTTPClient()
+		if err != nil {
+			return data, err
+		}
 		oauth = rh.originCloud.ToOAuth2Config()
 		oauth.RedirectURL = rh.oauthCallback
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, http.DefaultClient)
-		token, err := oauth.Exchange(ctx, authCode)
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+		opts := append(rh.originCloud.TokenOptions(), codeVerifierOptions(data.CodeVerifier)...)
+		token, err := oauth.Exchange(ctx, authCode, opts...)
 		if err != nil {
 			return data, fmt.Errorf("cannot exchange origin cloud authorization code for access token: %v", err)
 		}
-		data.LinkedAccount.OriginCloud.AccessToken = store.AccessToken(token.AccessToken)
-		data.LinkedAccount.OriginCloud.Expiry = token.Expiry
-		data.LinkedAccount.OriginCloud.RefreshToken = token.RefreshToken
-		data.State = LinkedAccountState_PROVISIONED_ORIGIN_CLOUD
-		return data, nil
+		return rh.provisionToken(data, token)
 	case LinkedAccountState_PROVISIONED_ORIGIN_CLOUD:
 		var h LinkedCloudHandler
 		err := rh.store.LoadLinkedClouds(ctx, store.Query{ID: data.LinkedAccount.TargetCloud.LinkedCloudID}, &h)
 		if err != nil {
 			return data, fmt.Errorf("cannot find linked cloud with ID %v: %v", data.LinkedAccount.TargetCloud.LinkedCloudID, err)
 		}
+		client, err := h.linkedCloud.HTTPClient()
+		if err != nil {
+			return data, err
+		}
 		oauth = h.linkedCloud.ToOAuth2Config()
 		oauth.RedirectURL = rh.oauthCallback
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, http.DefaultClient)
-		token, err := oauth.Exchange(ctx, authCode)
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+		opts := append(h.linkedCloud.TokenOptions(), codeVerifierOptions(data.CodeVerifier)...)
+		token, err := oauth.Exchange(ctx, authCode, opts...)
 		if err != nil {
 			return data, fmt.Errorf("cannot exchange target cloud authorization code for access token: %v", err)
 		}
-		data.LinkedAccount.TargetCloud.AccessToken = store.AccessToken(token.AccessToken)
-		data.LinkedAccount.TargetCloud.Expiry = token.Expiry
-		data.LinkedAccount.TargetCloud.RefreshToken = token.RefreshToken
-		data.State = LinkedAccountState_PROVISIONED_TARGET_CLOUD
-		return data, nil
+		return rh.provisionToken(data, token)
 	case LinkedAccountState_PROVISIONED_TARGET_CLOUD:
 		return data, nil
 	}
 	return data, fmt.Errorf("unknown state %v", data.State)
 }
 
-func (rh *RequestHandler) oAuthCallback(w http.ResponseWriter, r *http.Request) (int, error) {
-	authCode := r.FormValue("code")
-	state := r.FormValue("state")
-
-	linkedAccountData, ok := rh.provisionCache.Get(string(state))
-	if !ok {
-		return http.StatusBadRequest, fmt.Errorf("invalid/expired OAuth state")
+// provisionToken sets the token obtained by the current step to the linked account and moves the data to the next step.
+// The origin cloud token must belong to the user who becomes the owner of the linked account.
+func (rh *RequestHandler) provisionToken(data LinkedAccountData, token *oauth2.Token) (LinkedAccountData, error) {
+	switch data.State {
+	case LinkedAccountState_START:
+		data.LinkedAccount.OriginCloud.AccessToken = store.AccessToken(token.AccessToken)
+		data.LinkedAccount.OriginCloud.Expiry = token.Expiry
+		data.LinkedAccount.OriginCloud.RefreshToken = token.RefreshToken
+		userID, err := verifyOriginToken(rh.subManager.originValidator, data.LinkedAccount.OriginCloud.AccessToken)
+		if err != nil {
+			return data, fmt.Errorf("cannot verify origin cloud access token: %v", err)
+		}
+		data.LinkedAccount.UserID = userID
+		data.State = LinkedAccountState_PROVISIONED_ORIGIN_CLOUD
+	case LinkedAccountState_PROVISIONED_ORIGIN_CLOUD:
+		data.LinkedAccount.TargetCloud.AccessToken = store.AccessToken(token.AccessToken)
+		data.LinkedAccount.TargetCloud.Expiry = token.Expiry
+		data.LinkedAccount.TargetCloud.RefreshToken = token.RefreshToken
+		data.State = LinkedAccountState_PROVISIONED_TARGET_CLOUD
+	default:
+		return data, fmt.Errorf("state %v cannot provision token", data.State)
 	}
-	rh.provisionCache.Delete(string(state))
+	data.CodeVerifier = ""
+	return data, nil
+}
 
-	data := linkedAccountData.(LinkedAccountData)
-	newData, err := rh.HandleLinkedAccount(r.Context(), data, authCode)
+// handleOAuthCallback processes the step of the linking. It returns the linked account ID when the linking is finished.
+func (rh *RequestHandler) handleOAuthCallback(w http.ResponseWriter, r *http.Request, data LinkedAccountData) (string, int, error) {
+	if authErr := r.FormValue(ErrorKey); authErr != "" {
+		return "", http.StatusBadRequest, fmt.Errorf("authorization server returned error %v: %v", authErr, r.FormValue("error_description"))
+	}
+	newData, err := rh.HandleLinkedAccount(r.Context(), data, r.FormValue("code"))
 	if err != nil {
-		return http.StatusBadRequest, err
+		return "", http.StatusBadRequest, err
 	}
 
 	switch newData.State {
 	case LinkedAccountState_PROVISIONED_ORIGIN_CLOUD:
-		return rh.HandleOAuth(w, r, newData)
-	case LinkedAccountState_PROVISIONED_TARGET_CLOUD:
-		id, err := uuid.NewV4()
-		if err != nil {
-			return http.StatusInternalServerError, err
+		if newData.Reauthorize != "" {
+			var statusCode int
+			newData, statusCode, err = rh.verifyReauthorization(r.Context(), newData)
+			if err != nil {
+				return "", statusCode, err
+			}
+			if newData.Reauthorize == LinkedAccountSide_ORIGIN {
+				statusCode, err = rh.finishReauthorization(r.Context(), newData)
+				return newData.LinkedAccount.ID, statusCode, err
+			}
 		}
-		newData.LinkedAccount.ID = id.String()
-		err = rh.store.InsertLinkedAccount(r.Context(), newData.LinkedAccount)
-		if err != nil {
-			return http.StatusBadRequest, fmt.Errorf("cannot store linked account for url %v: %v", data.LinkedAccount.TargetURL, err)
+		statusCode, err := rh.HandleOAuth(w, r, newData)
+		return "", statusCode, err
+	case LinkedAccountState_PROVISIONED_TARGET_CLOUD:
+		if newData.Reauthorize != "" {
+			statusCode, err := rh.finishReauthorization(r.Context(), newData)
+			return newData.LinkedAccount.ID, statusCode, err
 		}
-		err = rh.subManager.StartSubscriptions(r.Context(), newData.LinkedAccount)
-		if err != nil {
-			rh.store.RemoveLinkedAccount(r.Context(), newData.LinkedAccount.ID)
-			return http.StatusBadRequest, fmt.Errorf("cannot start subscriptions %v: %v", data.LinkedAccount.TargetURL, err)
+		l, statusCode, err := rh.insertLinkedAccount(r.Context(), newData.LinkedAccount)
+		return l.ID, statusCode, err
+	}
+	return "", http.StatusInternalServerError, fmt.Errorf("invalid linked account state - %v", newData.State)
+}
+
+// insertLinkedAccount stores the new linked account and starts its subscriptions. The linked account is removed
+// when the subscriptions cannot be started.
+func (rh *RequestHandler) insertLinkedAccount(ctx context.Context, l store.LinkedAccount) (store.LinkedAccount, int, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return l, http.StatusInternalServerError, err
+	}
+	l.ID = id.String()
+	err = rh.store.InsertLinkedAccount(ctx, l)
+	if err != nil {
+		return l, http.StatusBadRequest, fmt.Errorf("cannot store linked account for url %v: %v", l.TargetURL, err)
+	}
+	err = rh.subManager.StartSubscriptions(ctx, l)
+	if err != nil {
+		rh.store.RemoveLinkedAccount(ctx, l.ID)
+		return l, http.StatusBadRequest, fmt.Errorf("cannot start subscriptions %v: %v", l.TargetURL, err)
+	}
+	return l, http.StatusOK, nil
+}
+
+func (rh *RequestHandler) oAuthCallback(w http.ResponseWriter, r *http.Request) (int, error) {
+	state := r.FormValue("state")
+
+	data, err := rh.pendingLinks.Pop(r.Context(), state)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid/expired OAuth state: %v", err)
+	}
+	if data.DeviceAuthorization.DeviceCode != "" {
+		return http.StatusBadRequest, fmt.Errorf("invalid OAuth state: it belongs to the device authorization")
+	}
+
+	linkedAccountID, statusCode, err := rh.handleOAuthCallback(w, r, data)
+	if data.RedirectURI == "" {
+		return statusCode, err
+	}
+	params := url.Values{}
+	switch {
+	case err != nil:
+		log.Errorf("cannot process oauth callback: %v", err)
+		code := r.FormValue(ErrorKey)
+		if code == "" {
+			code = errorCode(statusCode)
 		}
-		return http.StatusOK, nil
+		params.Set(ErrorKey, code)
+	case linkedAccountID != "":
+		params.Set(LinkedAccountIDKey, linkedAccountID)
+	default:
+		// the user was redirected to the next authorization server
+		return statusCode, nil
+	}
+	err = redirectToCaller(w, r, data.RedirectURI, params)
+	if err != nil {
+		return http.StatusInternalServerError, err
 	}
-	return http.StatusInternalServerError, fmt.Errorf("invalid linked account state - %v", newData.State)
+	return http.StatusOK, nil
 }
 
 func (rh *RequestHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// verifyOriginToken verifies the signature and expiry of an origin cloud access token against the JWKS of the origin cloud
+// and returns its subject.
+func verifyOriginToken(validator TokenValidator, token store.AccessToken) (string, error) {
+	c, err := parseOriginToken(validator, token)
+	if err != nil {
+		return "", err
+	}
+	return c.Subject, nil
+}
+
+// parseOriginToken verifies an origin cloud access token like verifyOriginToken and returns its claims.
+func parseOriginToken(validator TokenValidator, token store.AccessToken) (Claims, error) {
+	if validator == nil {
+		return Claims{}, fmt.Errorf("origin cloud token validator is not configured")
+	}
+	var c Claims
+	err := validator.ParseWithClaims(string(token), &c)
+	if err != nil {
+		return Claims{}, err
+	}
+	return c, nil
+}
+
+// getUserID returns the owner of the linked account after the origin cloud access token of the linked account is verified to belong to the user.
+func getUserID(validator TokenValidator, l store.LinkedAccount) (string, error) {
+	userID, err := verifyOriginToken(validator, l.OriginCloud.AccessToken)
+	if err != nil {
+		return "", fmt.Errorf("cannot verify origin cloud access token of linked account %v: %v", l.ID, err)
+	}
+	if l.UserID != "" && l.UserID != userID {
+		return "", fmt.Errorf("origin cloud access token of linked account %v belongs to another user", l.ID)
+	}
+	return userID, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"testing"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserID(t *testing.T) {
+	server, key := newTestJwks(t)
+	defer server.Close()
+	validator := kitJwt.NewValidator(server.URL, tls.Config{})
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	valid := jwtgo.MapClaims{"sub": "testUserID", "exp": time.Now().Add(time.Hour).Unix()}
+	expired := jwtgo.MapClaims{"sub": "testUserID", "exp": time.Now().Add(-time.Hour).Unix()}
+
+	tests := []struct {
+		name      string
+		validator TokenValidator
+		l         store.LinkedAccount
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			validator: validator,
+			l:         store.LinkedAccount{UserID: "testUserID", OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, valid))}},
+			want:      "testUserID",
+		},
+		{
+			name:      "without stored user",
+			validator: validator,
+			l:         store.LinkedAccount{OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, valid))}},
+			want:      "testUserID",
+		},
+		{
+			name:      "another user",
+			validator: validator,
+			l:         store.LinkedAccount{UserID: "anotherUserID", OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, valid))}},
+			wantErr:   true,
+		},
+		{
+			name:      "expired",
+			validator: validator,
+			l:         store.LinkedAccount{UserID: "testUserID", OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, expired))}},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid signature",
+			validator: validator,
+			l:         store.LinkedAccount{UserID: "testUserID", OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, otherKey, valid))}},
+			wantErr:   true,
+		},
+		{
+			name:      "unsigned",
+			validator: validator,
+			l:         store.LinkedAccount{UserID: "testUserID", OriginCloud: store.OAuth{AccessToken: "eyJhbGciOiJub25lIn0.eyJzdWIiOiJ0ZXN0VXNlcklEIn0."}},
+			wantErr:   true,
+		},
+		{
+			name:    "missing validator",
+			l:       store.LinkedAccount{UserID: "testUserID", OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, valid))}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getUserID(tt.validator, tt.l)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
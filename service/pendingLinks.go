@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// PendingLinkStore keeps LinkedAccountData of unfinished account linkings between the OAuth authorization request
+// and its callback. It must be shared by all replicas of the service.
+type PendingLinkStore interface {
+	// Add stores the data under the OAuth state. It fails when the state is already used.
+	Add(ctx context.Context, state string, data LinkedAccountData) error
+	// Pop returns and removes the data. It fails when the state is unknown or expired.
+	Pop(ctx context.Context, state string) (LinkedAccountData, error)
+}
+
+type storePendingLinkStore struct {
+	store      store.Store
+	expiration time.Duration
+}
+
+// NewPendingLinkStore creates PendingLinkStore persisting pending links to the store. They expire after expiration.
+func NewPendingLinkStore(s store.Store, expiration time.Duration) PendingLinkStore {
+	return &storePendingLinkStore{
+		store:      s,
+		expiration: expiration,
+	}
+}
+
+func (p *storePendingLinkStore) Add(ctx context.Context, state string, data LinkedAccountData) error {
+	d, err := json.Encode(data)
+	if err != nil {
+		return fmt.Errorf("cannot encode linked account data: %v", err)
+	}
+	return p.store.InsertPendingLink(ctx, store.PendingLink{
+		State:  state,
+		Data:   d,
+		Expiry: time.Now().Add(p.expiration),
+	})
+}
+
+func (p *storePendingLinkStore) Pop(ctx context.Context, state string) (LinkedAccountData, error) {
+	link, err := p.store.PopPendingLink(ctx, state)
+	if err != nil {
+		return LinkedAccountData{}, err
+	}
+	var data LinkedAccountData
+	err = json.Decode(link.Data, &data)
+	if err != nil {
+		return LinkedAccountData{}, fmt.Errorf("cannot decode linked account data: %v", err)
+	}
+	return data, nil
+}
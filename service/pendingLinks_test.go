@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPendingLinkStore(t *testing.T) {
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	data := LinkedAccountData{
+		LinkedAccount: store.LinkedAccount{
+			UserID:    "testUserID",
+			TargetURL: "testTargetURL",
+			TargetCloud: store.OAuth{
+				LinkedCloudID: "testLinkedCloudID",
+			},
+			OriginCloud: store.OAuth{
+				AccessToken:  "testAccessToken",
+				RefreshToken: "testRefreshToken",
+				Expiry:       time.Unix(1600000000, 0),
+			},
+		},
+		State: LinkedAccountState_PROVISIONED_ORIGIN_CLOUD,
+	}
+
+	// replicas share the store
+	p1 := NewPendingLinkStore(s, time.Minute)
+	p2 := NewPendingLinkStore(s, time.Minute)
+
+	require.NoError(t, p1.Add(ctx, "testState", data))
+	assert.Error(t, p1.Add(ctx, "testState", data))
+
+	got, err := p2.Pop(ctx, "testState")
+	require.NoError(t, err)
+	assert.Equal(t, data.State, got.State)
+	assert.Equal(t, data.LinkedAccount.UserID, got.LinkedAccount.UserID)
+	assert.Equal(t, data.LinkedAccount.TargetCloud, got.LinkedAccount.TargetCloud)
+	assert.Equal(t, data.LinkedAccount.OriginCloud.AccessToken, got.LinkedAccount.OriginCloud.AccessToken)
+	assert.True(t, data.LinkedAccount.OriginCloud.Expiry.Equal(got.LinkedAccount.OriginCloud.Expiry))
+
+	_, err = p1.Pop(ctx, "testState")
+	assert.Error(t, err)
+
+	expired := NewPendingLinkStore(s, -time.Minute)
+	require.NoError(t, expired.Add(ctx, "testExpiredState", data))
+	_, err = p1.Pop(ctx, "testExpiredState")
+	assert.Error(t, err)
+}
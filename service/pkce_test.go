@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// RFC 7636 appendix B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", codeChallengeS256("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	v, err := newCodeVerifier()
+	require.NoError(t, err)
+	assert.Len(t, v, 43)
+	assert.NotContains(t, v, "=")
+}
+
+func TestHandleOAuthPKCE(t *testing.T) {
+	originCloud := store.LinkedCloud{
+		ClientID: "testClientID",
+		Scopes:   []string{"testScope"},
+		Endpoint: store.Endpoint{AuthUrl: "https://origin/authorize", TokenUrl: "https://origin/token"},
+	}
+	tests := []struct {
+		name string
+		pkce bool
+	}{
+		{name: "without PKCE"},
+		{name: "with PKCE", pkce: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originCloud.PKCE = tt.pkce
+			pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+			rh := NewRequestHandler(originCloud, "https://connector/callback", nil, nil, nil, nil, nil, pendingLinks, nil, nil, nil)
+
+			w := httptest.NewRecorder()
+			_, err := rh.HandleOAuth(w, httptest.NewRequest(http.MethodGet, "/", nil), LinkedAccountData{})
+			require.NoError(t, err)
+			require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			u, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			q := u.Query()
+
+			data, err := pendingLinks.Pop(context.Background(), q.Get("state"))
+			require.NoError(t, err)
+			if !tt.pkce {
+				assert.Empty(t, data.CodeVerifier)
+				assert.Empty(t, q.Get("code_challenge"))
+				return
+			}
+			require.NotEmpty(t, data.CodeVerifier)
+			assert.Equal(t, "S256", q.Get("code_challenge_method"))
+			assert.Equal(t, codeChallengeS256(data.CodeVerifier), q.Get("code_challenge"))
+		})
+	}
+}
+
+func TestHandleLinkedAccountSendsCodeVerifier(t *testing.T) {
+	var codeVerifier string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		codeVerifier = r.PostForm.Get("code_verifier")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"testAccessToken","refresh_token":"testRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: tokenServer.URL + "/authorize", TokenUrl: tokenServer.URL + "/token"},
+		PKCE:         true,
+	}))
+	rh := NewRequestHandler(store.LinkedCloud{}, "https://connector/callback", nil, nil, nil, nil, s, nil, nil, nil, nil)
+
+	data, err := rh.HandleLinkedAccount(ctx, LinkedAccountData{
+		LinkedAccount: store.LinkedAccount{TargetCloud: store.OAuth{LinkedCloudID: "testID"}},
+		State:         LinkedAccountState_PROVISIONED_ORIGIN_CLOUD,
+		CodeVerifier:  "testCodeVerifier",
+	}, "testCode")
+	require.NoError(t, err)
+	assert.Equal(t, "testCodeVerifier", codeVerifier)
+	assert.Equal(t, LinkedAccountState_PROVISIONED_TARGET_CLOUD, data.State)
+	assert.Empty(t, data.CodeVerifier)
+	assert.Equal(t, store.AccessToken("testAccessToken"), data.LinkedAccount.TargetCloud.AccessToken)
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/gorilla/mux"
+)
+
+// reauthorizeLinkedAccount starts the authorization of an existing linked account. The user is always authorized
+// by the origin cloud first to prove the ownership of the linked account, then by the target cloud when side is target.
+func (rh *RequestHandler) reauthorizeLinkedAccount(w http.ResponseWriter, r *http.Request) (int, error) {
+	linkedAccountID, _ := mux.Vars(r)[linkedAccountIdKey]
+	side := LinkedAccountSide(r.FormValue("side"))
+	switch side {
+	case "":
+		side = LinkedAccountSide_TARGET
+	case LinkedAccountSide_ORIGIN, LinkedAccountSide_TARGET:
+	default:
+		return http.StatusBadRequest, fmt.Errorf("invalid side %v", side)
+	}
+	redirectURI, err := rh.parseRedirectURI(r)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	data := LinkedAccountData{
+		LinkedAccount: store.LinkedAccount{ID: linkedAccountID},
+		Reauthorize:   side,
+		RedirectURI:   redirectURI,
+	}
+	return rh.HandleOAuth(w, r, data)
+}
+
+func (rh *RequestHandler) ReauthorizeLinkedAccount(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.reauthorizeLinkedAccount(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot reauthorize linked account: %v", err), statusCode, w)
+	}
+}
+
+// verifyReauthorization checks that the user authorized by the origin cloud owns the linked account
+// and completes the data of the target cloud step.
+func (rh *RequestHandler) verifyReauthorization(ctx context.Context, data LinkedAccountData) (LinkedAccountData, int, error) {
+	var h LinkedAccountHandler
+	err := rh.store.LoadLinkedAccounts(ctx, store.Query{ID: data.LinkedAccount.ID}, &h)
+	if err != nil || !h.ok {
+		return data, http.StatusNotFound, fmt.Errorf("cannot load linked account %v: not found", data.LinkedAccount.ID)
+	}
+	if h.linkedAccount.UserID == "" || h.linkedAccount.UserID != data.LinkedAccount.UserID {
+		return data, http.StatusForbidden, fmt.Errorf("linked account %v is not owned by the user", data.LinkedAccount.ID)
+	}
+	if data.Reauthorize == LinkedAccountSide_ORIGIN && h.linkedAccount.TargetReauthRequired {
+		return data, http.StatusBadRequest, fmt.Errorf("linked account %v requires reauthorization of the target cloud", data.LinkedAccount.ID)
+	}
+	data.LinkedAccount.TargetURL = h.linkedAccount.TargetURL
+	data.LinkedAccount.TargetCloud = store.OAuth{LinkedCloudID: h.linkedAccount.TargetCloud.LinkedCloudID}
+	return data, http.StatusOK, nil
+}
+
+// finishReauthorization replaces tokens of the linked account and resumes its subscriptions. Subscriptions
+// of devices are kept, so the devices stay registered.
+func (rh *RequestHandler) finishReauthorization(ctx context.Context, data LinkedAccountData) (int, error) {
+	var targetCloud *store.OAuth
+	if data.Reauthorize == LinkedAccountSide_TARGET {
+		targetCloud = &data.LinkedAccount.TargetCloud
+	}
+	l, err := store.ReauthorizeLinkedAccount(ctx, rh.store, data.LinkedAccount.ID, data.LinkedAccount.OriginCloud, targetCloud)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	rh.subManager.updateCachedLinkedAccount(l)
+	err = rh.subManager.ResumeSubscriptions(ctx, l)
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReauthorizeLinkedAccount(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	var accessToken string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"` + accessToken + `","refresh_token":"newRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	newCloud := func(id string) store.LinkedCloud {
+		return store.LinkedCloud{
+			ID:           id,
+			ClientID:     "testClientID",
+			ClientSecret: "testClientSecret",
+			Scopes:       []string{"testScope"},
+			Endpoint:     store.Endpoint{AuthUrl: tokenServer.URL + "/" + id + "/authorize", TokenUrl: tokenServer.URL + "/token"},
+		}
+	}
+	originCloud := newCloud("origin")
+
+	tests := []struct {
+		name                 string
+		side                 string
+		user                 string
+		targetReauthRequired bool
+		wantCode             int
+		wantLocation         string
+	}{
+		{name: "invalid side", side: "invalid", user: "user0", wantCode: http.StatusBadRequest},
+		{name: "another user", side: "origin", user: "user1", wantCode: http.StatusForbidden},
+		{name: "origin", side: "origin", user: "user0", wantCode: http.StatusOK},
+		{name: "origin - target reauth required", side: "origin", user: "user0", targetReauthRequired: true, wantCode: http.StatusBadRequest},
+		{name: "target", side: "target", user: "user0", wantCode: http.StatusTemporaryRedirect, wantLocation: tokenServer.URL + "/target/authorize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := inmemory.NewStore()
+			require.NoError(t, s.InsertLinkedCloud(ctx, newCloud("target")))
+			require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+				ID:                   "testID",
+				UserID:               "user0",
+				TargetURL:            "testTargetURL",
+				Status:               store.LinkedAccountStatus_REAUTH_REQUIRED,
+				OriginCloud:          store.OAuth{AccessToken: "oldAccessToken", RefreshToken: "revokedRefreshToken"},
+				TargetCloud:          store.OAuth{LinkedCloudID: "target", AccessToken: "targetAccessToken"},
+				TargetReauthRequired: tt.targetReauthRequired,
+			}))
+			_, err := s.FindOrCreateSubscription(ctx, store.Subscription{SubscriptionID: "testSubscriptionID", Type: store.Type_Devices, LinkedAccountID: "testID"})
+			require.NoError(t, err)
+			validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+			subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, validator, 0, 0)
+			pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+			rh := NewRequestHandler(originCloud, "https://connector/callback", subManager, nil, nil, nil, s, pendingLinks, newTestAuthorizer(jwks.URL), nil, nil)
+			h := NewHTTP(rh).Handler
+			accessToken = newTestToken(t, key, newTestUserClaims(tt.user, ""))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri.LinkedAccounts+"/testID/reauthorize?side="+tt.side, nil))
+			if tt.wantCode == http.StatusBadRequest && tt.side == "invalid" {
+				require.Equal(t, tt.wantCode, w.Code)
+				return
+			}
+			require.Equal(t, http.StatusTemporaryRedirect, w.Code)
+			u, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri.OAuthCallback+"?code=testCode&state="+u.Query().Get("state"), nil))
+			require.Equal(t, tt.wantCode, w.Code)
+
+			var lh LinkedAccountHandler
+			require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{ID: "testID"}, &lh))
+			if tt.wantLocation != "" {
+				assert.Contains(t, w.Header().Get("Location"), tt.wantLocation)
+			}
+			if tt.wantCode != http.StatusOK {
+				assert.Equal(t, store.AccessToken("oldAccessToken"), lh.linkedAccount.OriginCloud.AccessToken)
+				return
+			}
+			assert.Equal(t, store.AccessToken(accessToken), lh.linkedAccount.OriginCloud.AccessToken)
+			assert.Equal(t, "newRefreshToken", lh.linkedAccount.OriginCloud.RefreshToken)
+			assert.Equal(t, store.AccessToken("targetAccessToken"), lh.linkedAccount.TargetCloud.AccessToken)
+			assert.Equal(t, store.LinkedAccountStatus_ACTIVE, lh.linkedAccount.Status)
+		})
+	}
+}
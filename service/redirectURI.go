@@ -0,0 +1,62 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// RedirectURIKey query parameter of the caller's URI which receives the result of the linking
+	RedirectURIKey = "redirect_uri"
+	// LinkedAccountIDKey query parameter of the redirect_uri with the linked account ID when the linking succeeded
+	LinkedAccountIDKey = "linked_account_id"
+	// ErrorKey query parameter of the redirect_uri with the error code when the linking failed
+	ErrorKey = "error"
+)
+
+// Error codes passed to the redirect_uri. Errors returned by the authorization server, e.g. access_denied, are passed as they are.
+const (
+	ErrorCode_INVALID_REQUEST = "invalid_request"
+	ErrorCode_ACCESS_DENIED   = "access_denied"
+	ErrorCode_SERVER_ERROR    = "server_error"
+)
+
+// parseRedirectURI returns the redirect_uri of the request. It must equal one of the allowed redirect URIs.
+func (rh *RequestHandler) parseRedirectURI(r *http.Request) (string, error) {
+	redirectURI := r.FormValue(RedirectURIKey)
+	if redirectURI == "" {
+		return "", nil
+	}
+	for _, allowed := range rh.redirectURIs {
+		if redirectURI == allowed {
+			return redirectURI, nil
+		}
+	}
+	return "", fmt.Errorf("invalid %v %v: not allowed", RedirectURIKey, redirectURI)
+}
+
+func errorCode(statusCode int) string {
+	switch {
+	case statusCode == http.StatusForbidden || statusCode == http.StatusUnauthorized:
+		return ErrorCode_ACCESS_DENIED
+	case statusCode >= http.StatusInternalServerError:
+		return ErrorCode_SERVER_ERROR
+	}
+	return ErrorCode_INVALID_REQUEST
+}
+
+// redirectToCaller redirects the user to the redirect URI with params added to its query.
+func redirectToCaller(w http.ResponseWriter, r *http.Request, redirectURI string, params url.Values) error {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return fmt.Errorf("cannot parse %v %v: %v", RedirectURIKey, redirectURI, err)
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	http.Redirect(w, r, u.String(), http.StatusTemporaryRedirect)
+	return nil
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/uri"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRedirectURI(t *testing.T) {
+	rh := NewRequestHandler(store.LinkedCloud{}, "", nil, nil, nil, nil, nil, nil, nil, []string{"https://app/linked"}, nil)
+	tests := []struct {
+		name    string
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{name: "empty"},
+		{name: "allowed", query: "?redirect_uri=https://app/linked", want: "https://app/linked"},
+		{name: "another path", query: "?redirect_uri=https://app/other", wantErr: true},
+		{name: "another host", query: "?redirect_uri=https://evil/linked", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rh.parseRedirectURI(httptest.NewRequest(http.MethodGet, "/"+tt.query, nil))
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestOAuthCallbackRedirectsToCaller(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	accessToken := newTestToken(t, key, newTestUserClaims("user0", ""))
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("code") == "invalidCode" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"` + accessToken + `","refresh_token":"newRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	originCloud := store.LinkedCloud{
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: tokenServer.URL + "/authorize", TokenUrl: tokenServer.URL + "/token"},
+	}
+	reauthorize := LinkedAccountData{
+		LinkedAccount: store.LinkedAccount{ID: "testID"},
+		Reauthorize:   LinkedAccountSide_ORIGIN,
+		RedirectURI:   "https://app/linked?tab=clouds",
+	}
+	withoutRedirectURI := reauthorize
+	withoutRedirectURI.RedirectURI = ""
+
+	tests := []struct {
+		name      string
+		data      LinkedAccountData
+		query     string
+		wantCode  int
+		wantQuery url.Values
+	}{
+		{
+			name:      "linked",
+			data:      reauthorize,
+			query:     "code=testCode",
+			wantCode:  http.StatusTemporaryRedirect,
+			wantQuery: url.Values{"tab": {"clouds"}, LinkedAccountIDKey: {"testID"}},
+		},
+		{
+			name:      "denied by user",
+			data:      reauthorize,
+			query:     "error=access_denied",
+			wantCode:  http.StatusTemporaryRedirect,
+			wantQuery: url.Values{"tab": {"clouds"}, ErrorKey: {ErrorCode_ACCESS_DENIED}},
+		},
+		{
+			name:      "invalid code",
+			data:      reauthorize,
+			query:     "code=invalidCode",
+			wantCode:  http.StatusTemporaryRedirect,
+			wantQuery: url.Values{"tab": {"clouds"}, ErrorKey: {ErrorCode_INVALID_REQUEST}},
+		},
+		{
+			name:     "without redirect_uri",
+			data:     withoutRedirectURI,
+			query:    "code=invalidCode",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := inmemory.NewStore()
+			require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+				ID:          "testID",
+				UserID:      "user0",
+				TargetURL:   "testTargetURL",
+				TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken"},
+			}))
+			_, err := s.FindOrCreateSubscription(ctx, store.Subscription{SubscriptionID: "testSubscriptionID", Type: store.Type_Devices, LinkedAccountID: "testID"})
+			require.NoError(t, err)
+			subManager := NewSubscriptionManager("", nil, nil, s, nil, originCloud, kitJwt.NewValidator(jwks.URL, tls.Config{}), 0, 0)
+			pendingLinks := NewPendingLinkStore(inmemory.NewStore(), time.Minute)
+			require.NoError(t, pendingLinks.Add(ctx, "testState", tt.data))
+			rh := NewRequestHandler(originCloud, "https://connector/callback", subManager, nil, nil, nil, s, pendingLinks, nil, nil, nil)
+			h := NewHTTP(rh).Handler
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, uri.OAuthCallback+"?state=testState&"+tt.query, nil))
+			require.Equal(t, tt.wantCode, w.Code)
+			if tt.wantQuery == nil {
+				return
+			}
+			u, err := url.Parse(w.Header().Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, "https://app/linked", u.Scheme+"://"+u.Host+u.Path)
+			assert.Equal(t, tt.wantQuery, u.Query())
+		})
+	}
+}
@@ -2,9 +2,6 @@ package service
 
 import (
 	"net/http"
-	"time"
-
-	"github.com/patrickmn/go-cache"
 
 	"github.com/go-ocf/kit/log"
 	"github.com/go-ocf/openapi-connector/events"
@@ -20,6 +17,7 @@ import (
 
 const linkedCloudIdKey = "linkedCloudId"
 const linkedAccountIdKey = "linkedCloudId"
+const deviceAuthorizationIdKey = "deviceAuthorizationId"
 
 //RequestHandler for handling incoming request
 type RequestHandler struct {
@@ -31,8 +29,11 @@ type RequestHandler struct {
 	asClient pbAS.AuthorizationServiceClient
 	raClient pbRA.ResourceAggregateClient
 
-	provisionCache *cache.Cache
-	subManager     *SubscribeManager
+	pendingLinks PendingLinkStore
+	subManager   *SubscribeManager
+	authorizer   *Authorizer
+	redirectURIs []string
+	discovery    *Discovery
 }
 
 func logAndWriteErrorResponse(err error, statusCode int, w http.ResponseWriter) {
@@ -51,6 +52,10 @@ func NewRequestHandler(
 	raClient pbRA.ResourceAggregateClient,
 	resourceProjection *projectionRA.Projection,
 	store store.Store,
+	pendingLinks PendingLinkStore,
+	authorizer *Authorizer,
+	redirectURIs []string,
+	discovery *Discovery,
 ) *RequestHandler {
 	return &RequestHandler{
 		originCloud:        originCloud,
@@ -60,7 +65,10 @@ func NewRequestHandler(
 		raClient:           raClient,
 		resourceProjection: resourceProjection,
 		store:              store,
-		provisionCache:     cache.New(5*time.Minute, 10*time.Minute),
+		pendingLinks:       pendingLinks,
+		authorizer:         authorizer,
+		redirectURIs:       redirectURIs,
+		discovery:          discovery,
 	}
 }
 
@@ -84,22 +92,35 @@ func NewHTTP(requestHandler *RequestHandler) *http.Server {
 	// health check
 	r.HandleFunc("/", healthCheck).Methods("GET")
 
+	auth := requestHandler.authorizer
 	s := r.PathPrefix(uri.LinkedClouds).Subrouter()
 
 	// retrieve all linked clouds
-	s.HandleFunc("", requestHandler.RetrieveLinkedClouds).Methods("GET")
+	s.HandleFunc("", auth.Admin(requestHandler.RetrieveLinkedClouds)).Methods("GET")
 	// add linked cloud
-	s.HandleFunc("", requestHandler.AddLinkedCloud).Methods("POST")
+	s.HandleFunc("", auth.Admin(requestHandler.AddLinkedCloud)).Methods("POST")
+	// retrieve linked cloud with its ETag
+	s.HandleFunc("/{"+linkedCloudIdKey+"}", auth.Admin(requestHandler.RetrieveLinkedCloud)).Methods("GET")
 	// delete linked cloud
-	s.HandleFunc("/{"+linkedCloudIdKey+"}", requestHandler.DeleteLinkedCloud).Methods("DELETE")
+	s.HandleFunc("/{"+linkedCloudIdKey+"}", auth.Admin(requestHandler.DeleteLinkedCloud)).Methods("DELETE")
 
 	s = r.PathPrefix(uri.LinkedAccounts).Subrouter()
-	// add linked account
+	// add linked account - the user is authenticated by the origin cloud during linking
 	s.HandleFunc("", requestHandler.AddLinkedAccount).Methods("GET")
-	// retrieve all linked accounts
-	s.HandleFunc("/retrieve", requestHandler.RetrieveLinkedAccounts).Methods("GET")
-	// delete linked cloud
-	s.HandleFunc("/{"+linkedAccountIdKey+"}", requestHandler.DeleteLinkedAccount).Methods("DELETE")
+	// add linked account of a client credentials linked cloud - the user is authenticated by the origin cloud access token
+	s.HandleFunc("", requestHandler.AddClientCredentialsLinkedAccount).Methods("POST")
+	// add linked account by the device authorization grant - the user is authenticated by the origin cloud during linking
+	s.HandleFunc("/device", requestHandler.AddDeviceLinkedAccount).Methods("POST")
+	// poll the device authorization of the linked account
+	s.HandleFunc("/device/{"+deviceAuthorizationIdKey+"}", requestHandler.PollDeviceLinkedAccount).Methods("GET")
+	// retrieve linked accounts of the user
+	s.HandleFunc("/retrieve", auth.Authenticated(requestHandler.RetrieveLinkedAccounts)).Methods("GET")
+	// set status of linked account
+	s.HandleFunc("/{"+linkedAccountIdKey+"}/status", auth.Admin(requestHandler.UpdateLinkedAccountStatus)).Methods("PUT")
+	// reauthorize linked account - the owner is authenticated by the origin cloud
+	s.HandleFunc("/{"+linkedAccountIdKey+"}/reauthorize", requestHandler.ReauthorizeLinkedAccount).Methods("GET")
+	// delete linked account of the user
+	s.HandleFunc("/{"+linkedAccountIdKey+"}", auth.Authenticated(requestHandler.DeleteLinkedAccount)).Methods("DELETE")
 
 	// notify linked cloud
 	r.HandleFunc(uri.NotifyLinkedAccount, requestHandler.NotifyLinkedAccount).Methods("POST")
@@ -107,5 +128,8 @@ func NewHTTP(requestHandler *RequestHandler) *http.Server {
 	// OAuthCallback
 	r.HandleFunc(uri.OAuthCallback, requestHandler.OAuthCallback).Methods("GET")
 
+	// metrics of the service, e.g. out-of-order events
+	r.HandleFunc(uri.Metrics, auth.Admin(requestHandler.RetrieveMetrics)).Methods("GET")
+
 	return &http.Server{Handler: r}
 }
@@ -32,13 +32,17 @@ type resourceCtx struct {
 	pendingContentUpdate []raEvents.ResourceContentUpdatePending
 	store                store.Store
 	raClient             pbRA.ResourceAggregateClient
+	originCloud          store.LinkedCloud
+	originValidator      TokenValidator
 }
 
-func newResourceCtx(store store.Store, raClient pbRA.ResourceAggregateClient) func(context.Context) (eventstore.Model, error) {
+func newResourceCtx(store store.Store, raClient pbRA.ResourceAggregateClient, originCloud store.LinkedCloud, originValidator TokenValidator) func(context.Context) (eventstore.Model, error) {
 	return func(context.Context) (eventstore.Model, error) {
 		return &resourceCtx{
 			store:                store,
 			raClient:             raClient,
+			originCloud:          originCloud,
+			originValidator:      originValidator,
 			pendingContentUpdate: make([]raEvents.ResourceContentUpdatePending, 0, 8),
 		}, nil
 	}
@@ -75,9 +79,7 @@ func makeUpdateHref(url, deviceID, href string) string {
 	return url + kitHttp.CanonicalHref("devices/"+deviceID+"/"+href)
 }
 
-func updateDeviceResource(deviceID, href, contentType string, content []byte, l store.LinkedAccount) (string, []byte, pbRA.Status, error) {
-	client := http.Client{}
-
+func updateDeviceResource(client *http.Client, deviceID, href, contentType string, content []byte, l store.LinkedAccount) (string, []byte, pbRA.Status, error) {
 	r, w := io.Pipe()
 
 	req, err := http.NewRequest("POST", makeUpdateHref(l.TargetURL, deviceID, href), r)
@@ -149,23 +151,34 @@ func (m *resourceCtx) onPendingContentUpdate(ctx context.Context) error {
 		return fmt.Errorf("linked account not found")
 	}
 
-	linkedAccount, err := lah.linkedAccount.RefreshTokens(ctx, m.store)
+	if lah.linkedAccount.IsDisabled() {
+		return fmt.Errorf("linked account %v is disabled", lah.linkedAccount.ID)
+	}
+	linkedAccount, err := lah.linkedAccount.RefreshTokens(ctx, m.store, m.originCloud)
 	if err != nil {
 		return err
 	}
 
-	userID, err := linkedAccount.OriginCloud.AccessToken.GetSubject()
+	userID, err := getUserID(m.originValidator, linkedAccount)
 	if err != nil {
 		return fmt.Errorf("cannot get userID: %v", err)
 	}
+	client, err := linkedAccount.TargetHTTPClient(ctx, m.store)
+	if err != nil {
+		return err
+	}
 
 	for {
 		if len(m.pendingContentUpdate) == 0 {
 			break
 		}
-		contentType, content, status, err := updateDeviceResource(m.resource.DeviceId, m.resource.Href, m.pendingContentUpdate[0].Content.ContentType, m.pendingContentUpdate[0].Content.Data, linkedAccount)
+		contentType, content, status, err := updateDeviceResource(client, m.resource.DeviceId, m.resource.Href, m.pendingContentUpdate[0].Content.ContentType, m.pendingContentUpdate[0].Content.Data, linkedAccount)
 		if err != nil {
-			log.Errorf("cannot update content of device %v resource %v: %v", m.resource.DeviceId, m.resource.Href, err)
+			err = fmt.Errorf("cannot update content of device %v resource %v: %v", m.resource.DeviceId, m.resource.Href, err)
+			log.Errorf("%v", err)
+			recordLinkedAccountFailure(ctx, m.store, linkedAccount, err)
+		} else {
+			recordLinkedAccountSuccess(ctx, m.store, linkedAccount)
 		}
 		coapContentFormat := int32(-1)
 
@@ -15,7 +15,11 @@ import (
 )
 
 func (s *SubscribeManager) subscribeToResource(ctx context.Context, l store.LinkedAccount, correlationID, signingSecret, deviceID, resourceHrefLink string) (string, error) {
-	resp, err := subscribe(ctx, "/devices/"+deviceID+"/"+resourceHrefLink+"/subscriptions", correlationID, events.SubscriptionRequest{
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return "", err
+	}
+	resp, err := subscribe(ctx, client, "/devices/"+deviceID+"/"+resourceHrefLink+"/subscriptions", correlationID, events.SubscriptionRequest{
 		URL:           s.eventsURL,
 		EventType:     []events.EventType{events.EventType_ResourceContentChanged},
 		SigningSecret: signingSecret,
@@ -26,8 +30,12 @@ func (s *SubscribeManager) subscribeToResource(ctx context.Context, l store.Link
 	return resp.SubscriptionId, nil
 }
 
-func cancelResourceSubscription(ctx context.Context, l store.LinkedAccount, deviceID, resourceID, subscriptionID string) error {
-	err := cancelSubscription(ctx, "/devices/"+deviceID+"/"+resourceID+"/subscriptions/"+subscriptionID, l)
+func (s *SubscribeManager) cancelResourceSubscription(ctx context.Context, l store.LinkedAccount, deviceID, resourceID, subscriptionID string) error {
+	client, err := l.TargetHTTPClient(ctx, s.store)
+	if err != nil {
+		return err
+	}
+	err = cancelSubscription(ctx, client, "/devices/"+deviceID+"/"+resourceID+"/subscriptions/"+subscriptionID, l)
 	if err != nil {
 		return fmt.Errorf("cannot cancel resource subscription for %v: %v", l.ID, err)
 	}
@@ -35,11 +43,6 @@ func cancelResourceSubscription(ctx context.Context, l store.LinkedAccount, devi
 }
 
 func (s *SubscribeManager) HandleResourceContentChangedEvent(ctx context.Context, subscriptionData subscriptionData, header events.EventHeader, body []byte) error {
-	userID, err := subscriptionData.linkedAccount.OriginCloud.AccessToken.GetSubject()
-	if err != nil {
-		return fmt.Errorf("cannot get userID for device (%v) resource (%v) content changed: %v", subscriptionData.subscription.DeviceID, subscriptionData.subscription.Href, err)
-	}
-
 	coapContentFormat := int32(-1)
 	switch header.ContentType {
 	case coap.AppCBOR.String():
@@ -50,9 +53,9 @@ func (s *SubscribeManager) HandleResourceContentChangedEvent(ctx context.Context
 		coapContentFormat = int32(coap.AppJSON)
 	}
 
-	_, err = s.raClient.NotifyResourceContentChanged(ctx, &pbRA.NotifyResourceContentChangedRequest{
+	_, err := s.raClient.NotifyResourceContentChanged(ctx, &pbRA.NotifyResourceContentChangedRequest{
 		AuthorizationContext: &pbCQRS.AuthorizationContext{
-			UserId:      userID,
+			UserId:      subscriptionData.userID,
 			AccessToken: string(subscriptionData.linkedAccount.OriginCloud.AccessToken),
 			DeviceId:    subscriptionData.subscription.DeviceID,
 		},
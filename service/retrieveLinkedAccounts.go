@@ -20,13 +20,29 @@ func (h *LinkedAccountsHandler) Handle(ctx context.Context, iter store.LinkedAcc
 	return iter.Err()
 }
 
+// linkedAccountsQuery restricts the query to linked accounts of the caller unless the caller is an admin.
+func (rh *RequestHandler) linkedAccountsQuery(ctx context.Context, query store.Query) (store.Query, error) {
+	c, ok := ClaimsFromContext(ctx)
+	if !ok {
+		return query, fmt.Errorf("unauthenticated request")
+	}
+	if !rh.authorizer.IsAdmin(c) {
+		query.UserID = c.Subject
+	}
+	return query, nil
+}
+
 func (rh *RequestHandler) retrieveLinkedAccounts(w http.ResponseWriter, r *http.Request) (int, error) {
+	query, err := rh.linkedAccountsQuery(r.Context(), store.Query{})
+	if err != nil {
+		return http.StatusUnauthorized, err
+	}
 	var h LinkedAccountsHandler
-	err := rh.store.LoadLinkedAccounts(r.Context(), store.Query{}, &h)
+	err = rh.store.LoadLinkedAccounts(r.Context(), query, &h)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	err = writeJson(w, h.linkedAccounts)
+	err = writeJson(w, makeLinkedAccountsResponse(h.linkedAccounts))
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
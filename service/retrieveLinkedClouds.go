@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/go-ocf/openapi-connector/store"
+	"github.com/gorilla/mux"
 )
 
 type LinkedCloudsHandler struct {
@@ -26,7 +27,7 @@ func (rh *RequestHandler) retrieveLinkedClouds(w http.ResponseWriter, r *http.Re
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
-	err = writeJson(w, h.linkedClouds)
+	err = writeJson(w, makeLinkedCloudsResponse(h.linkedClouds))
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -39,3 +40,35 @@ func (rh *RequestHandler) RetrieveLinkedClouds(w http.ResponseWriter, r *http.Re
 		logAndWriteErrorResponse(fmt.Errorf("cannot retrieve linked clouds: %v", err), statusCode, w)
 	}
 }
+
+func (rh *RequestHandler) loadLinkedCloud(ctx context.Context, linkedCloudID string) (store.LinkedCloud, int, error) {
+	var h LinkedCloudsHandler
+	err := rh.store.LoadLinkedClouds(ctx, store.Query{ID: linkedCloudID}, &h)
+	if err != nil {
+		return store.LinkedCloud{}, http.StatusInternalServerError, err
+	}
+	if len(h.linkedClouds) != 1 {
+		return store.LinkedCloud{}, http.StatusNotFound, fmt.Errorf("linked cloud %v not found", linkedCloudID)
+	}
+	return h.linkedClouds[0], http.StatusOK, nil
+}
+
+func (rh *RequestHandler) retrieveLinkedCloud(w http.ResponseWriter, r *http.Request) (int, error) {
+	l, statusCode, err := rh.loadLinkedCloud(r.Context(), mux.Vars(r)[linkedCloudIdKey])
+	if err != nil {
+		return statusCode, err
+	}
+	w.Header().Set(ETagHeader, makeETag(l.Version))
+	err = writeJson(w, makeLinkedCloudResponse(l))
+	if err != nil {
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
+
+func (rh *RequestHandler) RetrieveLinkedCloud(w http.ResponseWriter, r *http.Request) {
+	statusCode, err := rh.retrieveLinkedCloud(w, r)
+	if err != nil {
+		logAndWriteErrorResponse(fmt.Errorf("cannot retrieve linked cloud: %v", err), statusCode, w)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/go-ocf/cqrs/eventbus"
 	cqrsEventStore "github.com/go-ocf/cqrs/eventstore"
 	"github.com/go-ocf/kit/log"
+	kitJwt "github.com/go-ocf/kit/security/jwt"
 	connectorStore "github.com/go-ocf/openapi-connector/store"
 	"google.golang.org/grpc/credentials"
 
@@ -21,10 +22,12 @@ import (
 
 //Server handle HTTP request
 type Server struct {
-	server  *http.Server
-	cfg     Config
-	handler *RequestHandler
-	ln      net.Listener
+	server    *http.Server
+	cfg       Config
+	handler   *RequestHandler
+	ln        net.Listener
+	refresher *TokenRefresher
+	discovery *Discovery
 }
 
 type loadDeviceSubscriptionsHandler struct {
@@ -75,7 +78,18 @@ func New(config Config, dialCertManager DialCertManager, listenCertManager Liste
 
 	ctx := context.Background()
 
-	resourceProjection, err := projectionRA.NewProjection(ctx, config.FQDN, resourceEventStore, resourceSubscriber, newResourceCtx(store, raClient))
+	discovery := NewDiscovery(config.Discovery, store)
+	config.OriginCloud, err = discovery.Resolve(ctx, config.OriginCloud)
+	if err != nil {
+		log.Fatalf("cannot create server: %v", err)
+	}
+	if config.OriginCloud.Endpoint.AuthUrl == "" || config.OriginCloud.Endpoint.TokenUrl == "" || config.OriginCloud.JwksURL == "" {
+		log.Fatalf("cannot create server: origin cloud requires AuthUrl, TokenUrl and JwksUrl or Issuer")
+	}
+
+	originValidator := kitJwt.NewValidator(config.OriginCloud.JwksURL, dialCertManager.GetClientTLSConfig())
+
+	resourceProjection, err := projectionRA.NewProjection(ctx, config.FQDN, resourceEventStore, resourceSubscriber, newResourceCtx(store, raClient, config.OriginCloud, originValidator))
 	if err != nil {
 		log.Fatalf("cannot create server: %v", err)
 	}
@@ -93,13 +107,17 @@ func New(config Config, dialCertManager DialCertManager, listenCertManager Liste
 		log.Fatalf("cannot create server: %v", err)
 	}
 
-	requestHandler := NewRequestHandler(config.OriginCloud, config.OAuthCallback, NewSubscriptionManager(config.EventsURL, authClient, raClient, store, resourceProjection), authClient, raClient, resourceProjection, store)
+	authorizer := NewAuthorizer(config.Authorization, kitJwt.NewValidator(config.Authorization.JwksURL, dialCertManager.GetClientTLSConfig()))
+
+	requestHandler := NewRequestHandler(config.OriginCloud, config.OAuthCallback, NewSubscriptionManager(config.EventsURL, authClient, raClient, store, resourceProjection, config.OriginCloud, originValidator, config.EventTimestampWindow, config.EventReorderTimeout), authClient, raClient, resourceProjection, store, NewPendingLinkStore(store, config.PendingLinkExpiration), authorizer, config.RedirectURIs, discovery)
 
 	server := Server{
-		server:  NewHTTP(requestHandler),
-		cfg:     config,
-		handler: requestHandler,
-		ln:      ln,
+		server:    NewHTTP(requestHandler),
+		cfg:       config,
+		handler:   requestHandler,
+		ln:        ln,
+		refresher: NewTokenRefresher(config.TokenRefresher, store, config.OriginCloud),
+		discovery: discovery,
 	}
 
 	return &server
@@ -107,6 +125,12 @@ func New(config Config, dialCertManager DialCertManager, listenCertManager Liste
 
 // Serve starts the service's HTTP server and blocks.
 func (s *Server) Serve() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// linked accounts stored before owners were persisted are visible only to admins until their owners are backfilled
+	go BackfillLinkedAccountOwners(ctx, s.handler.store, s.handler.originCloud, s.handler.subManager.originValidator)
+	go s.refresher.Run(ctx)
+	go s.discovery.Run(ctx)
 	return s.server.Serve(s.ln)
 }
 
@@ -31,23 +31,30 @@ type SubscribeManager struct {
 	asClient           pbAS.AuthorizationServiceClient
 	resourceProjection *projectionRA.Projection
 	cache              *cache.Cache
+	originCloud        store.LinkedCloud
+	originValidator    TokenValidator
+	// eventTimestampWindow is the maximal difference of Event-Timestamp from the current time. Zero disables the check.
+	eventTimestampWindow time.Duration
+	sequencer            *eventSequencer
 }
 
 func NewSubscriptionManager(EventsURL string, asClient pbAS.AuthorizationServiceClient, raClient pbRA.ResourceAggregateClient,
-	store store.Store, resourceProjection *projectionRA.Projection) *SubscribeManager {
+	store store.Store, resourceProjection *projectionRA.Projection, originCloud store.LinkedCloud, originValidator TokenValidator, eventTimestampWindow, eventReorderTimeout time.Duration) *SubscribeManager {
 	return &SubscribeManager{
-		eventsURL:          EventsURL,
-		store:              store,
-		raClient:           raClient,
-		asClient:           asClient,
-		cache:              cache.New(time.Minute*10, time.Minute*5),
-		resourceProjection: resourceProjection,
+		eventsURL:            EventsURL,
+		store:                store,
+		raClient:             raClient,
+		asClient:             asClient,
+		cache:                cache.New(time.Minute*10, time.Minute*5),
+		resourceProjection:   resourceProjection,
+		originCloud:          originCloud,
+		originValidator:      originValidator,
+		eventTimestampWindow: eventTimestampWindow,
+		sequencer:            newEventSequencer(store, eventReorderTimeout),
 	}
 }
 
-func subscribe(ctx context.Context, href, correlationID string, reqBody events.SubscriptionRequest, l store.LinkedAccount) (resp events.SubscriptionResponse, err error) {
-	client := http.Client{}
-
+func subscribe(ctx context.Context, client *http.Client, href, correlationID string, reqBody events.SubscriptionRequest, l store.LinkedAccount) (resp events.SubscriptionResponse, err error) {
 	r, w := io.Pipe()
 
 	req, err := http.NewRequest("POST", l.TargetURL+kitHttp.CanonicalHref(href), r)
@@ -81,8 +88,7 @@ func subscribe(ctx context.Context, href, correlationID string, reqBody events.S
 	return resp, nil
 }
 
-func cancelSubscription(ctx context.Context, href string, l store.LinkedAccount) error {
-	client := http.Client{}
+func cancelSubscription(ctx context.Context, client *http.Client, href string, l store.LinkedAccount) error {
 	req, err := http.NewRequest("DELETE", l.TargetURL+kitHttp.CanonicalHref(href), nil)
 	if err != nil {
 		return fmt.Errorf("cannot create delete request: %v", err)
@@ -126,7 +132,7 @@ func (s *SubscribeManager) HandleEvent(ctx context.Context, header events.EventH
 		subData.subscription.SubscriptionID = header.SubscriptionID
 		newSubscription, err := s.store.FindOrCreateSubscription(ctx, subData.subscription)
 		if err != nil {
-			cancelDevicesSubscription(ctx, subData.linkedAccount, subData.subscription.SubscriptionID)
+			s.cancelDevicesSubscription(ctx, subData.linkedAccount, subData.subscription.SubscriptionID)
 			return http.StatusGone, fmt.Errorf("cannot store subscription to DB: %v", err)
 		}
 		subData.subscription = newSubscription
@@ -161,13 +167,91 @@ func (s *SubscribeManager) HandleEvent(ctx context.Context, header events.EventH
 		return http.StatusBadRequest, fmt.Errorf("invalid event signature %v: %v", header.SubscriptionID, err)
 	}
 
+	release, statusCode, err := s.acceptEvent(ctx, header)
+	if err != nil {
+		return statusCode, err
+	}
+	applied := false
+	defer func() { release(applied) }()
+
 	s.cache.Set(header.CorrelationID, subData, cache.DefaultExpiration)
 
-	subData.linkedAccount, err = subData.linkedAccount.RefreshTokens(ctx, s.store)
+	if subData.linkedAccount.IsDisabled() {
+		return http.StatusForbidden, fmt.Errorf("cannot handle event %v: linked account %v is disabled", header.EventType, subData.linkedAccount.ID)
+	}
+
+	// failures of refresh are recorded to the linked account by RefreshTokens
+	subData.linkedAccount, err = subData.linkedAccount.RefreshTokens(ctx, s.store, s.originCloud)
 	if err != nil {
 		return http.StatusGone, fmt.Errorf("cannot refresh access token for linked account %v: %v", subData.linkedAccount.ID, err)
 	}
 
+	statusCode, err = s.handleEvent(ctx, header, body, subData)
+	if err != nil {
+		recordLinkedAccountFailure(ctx, s.store, subData.linkedAccount, err)
+		return statusCode, err
+	}
+	applied = true
+	recordLinkedAccountSuccess(ctx, s.store, subData.linkedAccount)
+	if header.EventType != events.EventType_SubscriptionCanceled {
+		s.saveSequenceNumber(ctx, header)
+	}
+	return http.StatusOK, nil
+}
+
+// acceptEvent rejects replayed events by StatusConflict. The event must be signed, because the signature covers
+// Event-Timestamp and Sequence-Number. The event waits for its turn in the order of the subscription, then its sequence
+// number is compared with the stored one, which is shared by all instances. The returned release function passes the turn
+// to the next event when the event was applied, otherwise the turn stays at the event, so the target cloud can deliver it again.
+func (s *SubscribeManager) acceptEvent(ctx context.Context, header events.EventHeader) (func(applied bool), int, error) {
+	if s.eventTimestampWindow > 0 {
+		d := time.Since(header.EventTimestamp)
+		if d > s.eventTimestampWindow || d < -s.eventTimestampWindow {
+			return nil, http.StatusConflict, fmt.Errorf("cannot accept event %v of subscription %v: event timestamp %v is out of the acceptance window", header.SequenceNumber, header.SubscriptionID, header.EventTimestamp)
+		}
+	}
+	release, err := s.sequencer.Wait(ctx, header.SubscriptionID, header.SequenceNumber)
+	if store.IsStaleSequenceNumberError(err) {
+		return nil, http.StatusConflict, err
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("cannot accept event %v of subscription %v: %v", header.SequenceNumber, header.SubscriptionID, err)
+	}
+	// the sequencer knows only events applied by this instance, another instance could apply the event meanwhile
+	var h SubscriptionHandler
+	err = s.store.LoadSubscriptions(ctx, []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: header.SubscriptionID}}, &h)
+	if err != nil {
+		release(false)
+		return nil, http.StatusInternalServerError, fmt.Errorf("cannot accept event %v of subscription %v: cannot load subscription from DB: %v", header.SequenceNumber, header.SubscriptionID, err)
+	}
+	if !h.ok {
+		release(false)
+		return nil, http.StatusGone, fmt.Errorf("cannot accept event %v: unknown subscription %v", header.SequenceNumber, header.SubscriptionID)
+	}
+	if header.SequenceNumber < h.subscription.NextSequenceNumber {
+		release(true)
+		return nil, http.StatusConflict, store.StaleSequenceNumberError{SubscriptionID: header.SubscriptionID, SequenceNumber: header.SequenceNumber}
+	}
+	return release, http.StatusOK, nil
+}
+
+// saveSequenceNumber stores the sequence number of the applied event, so its redelivery is rejected
+// also by other instances and after restart.
+func (s *SubscribeManager) saveSequenceNumber(ctx context.Context, header events.EventHeader) {
+	// the stored sequence number is stale when another instance applied the event or a later one meanwhile
+	err := s.store.UpdateSubscriptionSequenceNumber(ctx, header.SubscriptionID, header.SequenceNumber)
+	if err != nil {
+		log.Errorf("cannot save sequence number of event %v of subscription %v: %v", header.SequenceNumber, header.SubscriptionID, err)
+	}
+}
+
+func (s *SubscribeManager) handleEvent(ctx context.Context, header events.EventHeader, body []byte, subData subscriptionData) (int, error) {
+	var err error
+	subData.userID, err = getUserID(s.originValidator, subData.linkedAccount)
+	if err != nil {
+		return http.StatusUnauthorized, fmt.Errorf("cannot handle event %v: %v", header.EventType, err)
+	}
+
 	if header.EventType == events.EventType_SubscriptionCanceled {
 		err := s.HandleCancelEvent(ctx, header, subData.linkedAccount)
 		if err != nil {
@@ -223,6 +307,8 @@ func (s *SubscribeManager) HandleCancelEvent(ctx context.Context, header events.
 type subscriptionData struct {
 	linkedAccount store.LinkedAccount
 	subscription  store.Subscription
+	// userID is verified by the origin cloud access token of the linked account. It is set for each event.
+	userID string
 }
 
 func (s *SubscribeManager) StartSubscriptions(ctx context.Context, l store.LinkedAccount) error {
@@ -251,16 +337,43 @@ func (s *SubscribeManager) StartSubscriptions(ctx context.Context, l store.Linke
 	sub.SubscriptionID, err = s.subscribeToDevices(ctx, l, correlationID, signingSecret)
 	if err != nil {
 		s.cache.Delete(correlationID)
-		return fmt.Errorf("cannot subscribe to devices for %v: %v", l.ID, err)
+		err = fmt.Errorf("cannot subscribe to devices for %v: %v", l.ID, err)
+		recordLinkedAccountFailure(ctx, s.store, l, err)
+		return err
 	}
 	_, err = s.store.FindOrCreateSubscription(ctx, sub)
 	if err != nil {
-		cancelDevicesSubscription(ctx, l, sub.SubscriptionID)
+		s.cancelDevicesSubscription(ctx, l, sub.SubscriptionID)
 		return fmt.Errorf("cannot store subscription to DB: %v", err)
 	}
 	return nil
 }
 
+// ResumeSubscriptions starts subscriptions of the linked account when it isn't subscribed to devices.
+func (s *SubscribeManager) ResumeSubscriptions(ctx context.Context, l store.LinkedAccount) error {
+	var h SubscriptionsHandler
+	err := s.store.LoadSubscriptions(ctx, []store.SubscriptionQuery{store.SubscriptionQuery{LinkedAccountID: l.ID, Type: store.Type_Devices}}, &h)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %v", err)
+	}
+	if len(h.subscriptions) > 0 {
+		return nil
+	}
+	return s.StartSubscriptions(ctx, l)
+}
+
+// updateCachedLinkedAccount replaces the linked account in cached subscriptions, so events don't use replaced tokens.
+func (s *SubscribeManager) updateCachedLinkedAccount(l store.LinkedAccount) {
+	for correlationID, item := range s.cache.Items() {
+		subData, ok := item.Object.(subscriptionData)
+		if !ok || subData.linkedAccount.ID != l.ID {
+			continue
+		}
+		subData.linkedAccount = l
+		s.cache.Set(correlationID, subData, cache.DefaultExpiration)
+	}
+}
+
 type SubscriptionsHandler struct {
 	subscriptions []store.Subscription
 }
@@ -282,23 +395,23 @@ func (s *SubscribeManager) StopSubscriptions(ctx context.Context, l store.Linked
 	if len(h.subscriptions) == 0 {
 		return nil
 	}
-	linkedAccount, err := l.RefreshTokens(ctx, s.store)
+	linkedAccount, err := l.RefreshTokens(ctx, s.store, s.originCloud)
 
 	var errors []error
 	for _, sub := range h.subscriptions {
 		switch sub.Type {
 		case store.Type_Devices:
-			err = cancelDevicesSubscription(ctx, linkedAccount, sub.SubscriptionID)
+			err = s.cancelDevicesSubscription(ctx, linkedAccount, sub.SubscriptionID)
 			if err != nil {
 				errors = append(errors, err)
 			}
 		case store.Type_Device:
-			err = cancelDeviceSubscription(ctx, linkedAccount, sub.DeviceID, sub.SubscriptionID)
+			err = s.cancelDeviceSubscription(ctx, linkedAccount, sub.DeviceID, sub.SubscriptionID)
 			if err != nil {
 				errors = append(errors, err)
 			}
 		case store.Type_Resource:
-			err = cancelResourceSubscription(ctx, linkedAccount, sub.DeviceID, sub.Href, sub.SubscriptionID)
+			err = s.cancelResourceSubscription(ctx, linkedAccount, sub.DeviceID, sub.Href, sub.SubscriptionID)
 			if err != nil {
 				errors = append(errors, err)
 			}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/tls"
+	"net/http"
+	"testing"
+	"time"
+
+	kitJwt "github.com/go-ocf/kit/security/jwt"
+	"github.com/go-ocf/openapi-connector/events"
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEventStore(t *testing.T, key *rsa.PrivateKey) *inmemory.Store {
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+		ID:          "testLinkedAccountID",
+		TargetURL:   "testTargetURL",
+		TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "targetAccessToken"},
+		OriginCloud: store.OAuth{AccessToken: store.AccessToken(newTestToken(t, key, newTestUserClaims("user0", "")))},
+	}))
+	_, err := s.FindOrCreateSubscription(ctx, store.Subscription{
+		SubscriptionID:  "testSubscriptionID",
+		Type:            store.Type_Devices,
+		LinkedAccountID: "testLinkedAccountID",
+		SigningSecret:   "testSigningSecret",
+	})
+	require.NoError(t, err)
+	return s
+}
+
+func newTestEventHeader(sequenceNumber uint64, timestamp time.Time, secret string, body []byte) events.EventHeader {
+	h := events.EventHeader{
+		CorrelationID:  "testCorrelationID",
+		SubscriptionID: "testSubscriptionID",
+		ContentType:    events.ContentType_JSON,
+		EventType:      events.EventType_DevicesOnline,
+		SequenceNumber: sequenceNumber,
+		EventTimestamp: time.Unix(timestamp.Unix(), 0),
+	}
+	h.EventSignature = events.CalculateEventSignature(secret, h.ContentType, h.EventType, h.SubscriptionID, h.SequenceNumber, h.EventTimestamp, body)
+	return h
+}
+
+func TestHandleEventRejectsReplayedEvents(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	ctx := context.Background()
+	s := newTestEventStore(t, key)
+	validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+	subManager := NewSubscriptionManager("", nil, nil, s, nil, store.LinkedCloud{}, validator, time.Minute, 0)
+	now := time.Now()
+	devices := []byte("[]")
+	invalid := []byte("invalid")
+	tests := []struct {
+		name                   string
+		header                 events.EventHeader
+		body                   []byte
+		wantStatusCode         int
+		wantNextSequenceNumber uint64
+	}{
+		{name: "failed", header: newTestEventHeader(1, now, "testSigningSecret", invalid), body: invalid, wantStatusCode: http.StatusGone},
+		{name: "redelivered", header: newTestEventHeader(1, now, "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusOK, wantNextSequenceNumber: 2},
+		{name: "duplicate sequence number", header: newTestEventHeader(1, now, "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusConflict, wantNextSequenceNumber: 2},
+		{name: "lower sequence number", header: newTestEventHeader(0, now, "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusConflict, wantNextSequenceNumber: 2},
+		{name: "stale timestamp", header: newTestEventHeader(2, now.Add(-2*time.Minute), "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusConflict, wantNextSequenceNumber: 2},
+		{name: "future timestamp", header: newTestEventHeader(2, now.Add(2*time.Minute), "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusConflict, wantNextSequenceNumber: 2},
+		{name: "invalid signature", header: newTestEventHeader(2, now, "invalidSigningSecret", devices), body: devices, wantStatusCode: http.StatusBadRequest, wantNextSequenceNumber: 2},
+		{name: "next sequence number", header: newTestEventHeader(2, now, "testSigningSecret", devices), body: devices, wantStatusCode: http.StatusOK, wantNextSequenceNumber: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			statusCode, err := subManager.HandleEvent(ctx, tt.header, tt.body)
+			if tt.wantStatusCode == http.StatusOK {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+			assert.Equal(t, tt.wantStatusCode, statusCode)
+
+			var h SubscriptionHandler
+			require.NoError(t, s.LoadSubscriptions(ctx, []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: "testSubscriptionID"}}, &h))
+			require.True(t, h.ok)
+			assert.Equal(t, tt.wantNextSequenceNumber, h.subscription.NextSequenceNumber)
+		})
+	}
+}
+
+func TestHandleEventRejectsEventsAppliedByAnotherInstance(t *testing.T) {
+	jwks, key := newTestJwks(t)
+	defer jwks.Close()
+	ctx := context.Background()
+	s := newTestEventStore(t, key)
+	validator := kitJwt.NewValidator(jwks.URL, tls.Config{})
+	// instances share the store, each of them orders events by its own sequencer
+	instance0 := NewSubscriptionManager("", nil, nil, s, nil, store.LinkedCloud{}, validator, time.Minute, 0)
+	instance1 := NewSubscriptionManager("", nil, nil, s, nil, store.LinkedCloud{}, validator, time.Minute, 0)
+	body := []byte("[]")
+	now := time.Now()
+
+	statusCode, err := instance1.HandleEvent(ctx, newTestEventHeader(1, now, "testSigningSecret", body), body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+	statusCode, err = instance0.HandleEvent(ctx, newTestEventHeader(2, now, "testSigningSecret", body), body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+
+	// the sequencer of instance1 expects the event 2, but instance0 applied it already
+	statusCode, err = instance1.HandleEvent(ctx, newTestEventHeader(2, now, "testSigningSecret", body), body)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusConflict, statusCode)
+	statusCode, err = instance1.HandleEvent(ctx, newTestEventHeader(3, now, "testSigningSecret", body), body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, statusCode)
+}
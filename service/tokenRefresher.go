@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/go-ocf/kit/log"
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// TokenRefresherConfig configures the background refresh of linked account tokens.
+type TokenRefresherConfig struct {
+	// Interval between two scans of the linked accounts.
+	Interval time.Duration `envconfig:"TOKEN_REFRESH_INTERVAL" default:"1m"`
+	// Window tokens expiring within the window are refreshed.
+	Window time.Duration `envconfig:"TOKEN_REFRESH_WINDOW" default:"5m"`
+	// Jitter random duration up to the jitter extends the window, so replicas don't refresh the same tokens at once.
+	Jitter time.Duration `envconfig:"TOKEN_REFRESH_JITTER" default:"30s"`
+}
+
+// TokenRefresher refreshes tokens of linked accounts before they expire, so
+// incoming events are not delayed by refresh round-trips.
+type TokenRefresher struct {
+	cfg         TokenRefresherConfig
+	store       store.Store
+	originCloud store.LinkedCloud
+}
+
+// NewTokenRefresher creates a new TokenRefresher.
+func NewTokenRefresher(cfg TokenRefresherConfig, s store.Store, originCloud store.LinkedCloud) *TokenRefresher {
+	return &TokenRefresher{
+		cfg:         cfg,
+		store:       s,
+		originCloud: originCloud,
+	}
+}
+
+// Run refreshes tokens every interval until the ctx is done.
+func (r *TokenRefresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		r.RefreshExpiringTokens(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *TokenRefresher) deadline() time.Time {
+	d := time.Now().Add(r.cfg.Window)
+	if r.cfg.Jitter > 0 {
+		d = d.Add(time.Duration(rand.Int63n(int64(r.cfg.Jitter))))
+	}
+	return d
+}
+
+// RefreshExpiringTokens refreshes tokens of all linked accounts which expire within the window.
+// Linked accounts which require reauthorization or are disabled are skipped. Failures are logged.
+func (r *TokenRefresher) RefreshExpiringTokens(ctx context.Context) {
+	var h LinkedAccountsHandler
+	err := r.store.LoadLinkedAccounts(ctx, store.Query{}, &h)
+	if err != nil {
+		log.Errorf("cannot load linked accounts to refresh tokens: %v", err)
+		return
+	}
+	for _, l := range h.linkedAccounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if l.IsReauthRequired() || l.IsDisabled() {
+			continue
+		}
+		_, err := l.RefreshTokensExpiringBefore(ctx, r.store, r.originCloud, r.deadline())
+		if err != nil {
+			log.Errorf("cannot refresh tokens of linked account %v: %v", l.ID, err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTokenServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("refresh_token") == "revokedRefreshToken" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		if r.PostForm.Get("refresh_token") == "unavailableRefreshToken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"access_token":"newAccessToken","refresh_token":"newRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+}
+
+func TestTokenRefresher(t *testing.T) {
+	tokenServer := newTestTokenServer(t)
+	defer tokenServer.Close()
+
+	ctx := context.Background()
+	cloud := store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: tokenServer.URL + "/authorize", TokenUrl: tokenServer.URL + "/token"},
+	}
+	newLinkedAccount := func(id, refreshToken string, expiry time.Time) store.LinkedAccount {
+		return store.LinkedAccount{
+			ID:        id,
+			TargetURL: "testTargetURL",
+			TargetCloud: store.OAuth{
+				LinkedCloudID: cloud.ID,
+				AccessToken:   "oldAccessToken",
+				RefreshToken:  refreshToken,
+				Expiry:        expiry,
+			},
+		}
+	}
+	expiring := time.Now().Add(time.Minute)
+	tests := []struct {
+		name          string
+		linkedAccount store.LinkedAccount
+		want          store.LinkedAccount
+	}{
+		{
+			name:          "expiring",
+			linkedAccount: newLinkedAccount("expiring", "testRefreshToken", expiring),
+			want: store.LinkedAccount{
+				TargetCloud: store.OAuth{AccessToken: "newAccessToken", RefreshToken: "newRefreshToken"},
+			},
+		},
+		{
+			name:          "valid",
+			linkedAccount: newLinkedAccount("valid", "testRefreshToken", time.Now().Add(time.Hour)),
+			want: store.LinkedAccount{
+				TargetCloud: store.OAuth{AccessToken: "oldAccessToken", RefreshToken: "testRefreshToken"},
+			},
+		},
+		{
+			name:          "revoked",
+			linkedAccount: newLinkedAccount("revoked", "revokedRefreshToken", expiring),
+			want: store.LinkedAccount{
+				TargetCloud: store.OAuth{AccessToken: "oldAccessToken", RefreshToken: "revokedRefreshToken"},
+				Status:      store.LinkedAccountStatus_REAUTH_REQUIRED,
+			},
+		},
+		{
+			name:          "unavailable",
+			linkedAccount: newLinkedAccount("unavailable", "unavailableRefreshToken", expiring),
+			want: store.LinkedAccount{
+				TargetCloud: store.OAuth{AccessToken: "oldAccessToken", RefreshToken: "unavailableRefreshToken"},
+				Status:      store.LinkedAccountStatus_DEGRADED,
+			},
+		},
+	}
+
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, cloud))
+	for _, tt := range tests {
+		require.NoError(t, s.InsertLinkedAccount(ctx, tt.linkedAccount))
+	}
+	r := NewTokenRefresher(TokenRefresherConfig{Window: 5 * time.Minute}, s, store.LinkedCloud{})
+	r.RefreshExpiringTokens(ctx)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h LinkedAccountHandler
+			require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{ID: tt.linkedAccount.ID}, &h))
+			require.True(t, h.ok)
+			assert.Equal(t, tt.want.TargetCloud.AccessToken, h.linkedAccount.TargetCloud.AccessToken)
+			assert.Equal(t, tt.want.TargetCloud.RefreshToken, h.linkedAccount.TargetCloud.RefreshToken)
+			assert.Equal(t, tt.want.Status, h.linkedAccount.Status)
+		})
+	}
+}
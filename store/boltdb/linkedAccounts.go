@@ -0,0 +1,257 @@
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+type dbOAuth struct {
+	LinkedCloudID string
+	AccessToken   string
+	RefreshToken  string
+	Expiry        int64
+}
+
+type dbLinkedAccount struct {
+	ID                   string
+	UserID               string
+	TargetURL            string
+	TargetCloud          dbOAuth
+	OriginCloud          dbOAuth
+	Status               string
+	LastError            string
+	LastErrorAt          int64
+	StatusChangedAt      int64
+	Version              uint64
+	TargetReauthRequired bool
+}
+
+func makeDBOAuth(o store.OAuth) dbOAuth {
+	expiry := int64(0)
+	if !o.Expiry.IsZero() {
+		expiry = o.Expiry.UnixNano()
+	}
+	return dbOAuth{
+		LinkedCloudID: o.LinkedCloudID,
+		AccessToken:   string(o.AccessToken),
+		RefreshToken:  o.RefreshToken,
+		Expiry:        expiry,
+	}
+}
+
+func (o dbOAuth) toOAuth() store.OAuth {
+	var expiry time.Time
+	if o.Expiry != 0 {
+		expiry = time.Unix(0, o.Expiry)
+	}
+	return store.OAuth{
+		LinkedCloudID: o.LinkedCloudID,
+		AccessToken:   store.AccessToken(o.AccessToken),
+		RefreshToken:  o.RefreshToken,
+		Expiry:        expiry,
+	}
+}
+
+func makeDBLinkedAccount(sub store.LinkedAccount) dbLinkedAccount {
+	return dbLinkedAccount{
+		ID:                   sub.ID,
+		UserID:               sub.UserID,
+		TargetURL:            sub.TargetURL,
+		TargetCloud:          makeDBOAuth(sub.TargetCloud),
+		OriginCloud:          makeDBOAuth(sub.OriginCloud),
+		Status:               string(sub.Status),
+		LastError:            sub.LastError,
+		LastErrorAt:          makeDBTime(sub.LastErrorAt),
+		StatusChangedAt:      makeDBTime(sub.StatusChangedAt),
+		Version:              sub.Version,
+		TargetReauthRequired: sub.TargetReauthRequired,
+	}
+}
+
+func (sub dbLinkedAccount) toLinkedAccount() store.LinkedAccount {
+	return store.LinkedAccount{
+		ID:                   sub.ID,
+		UserID:               sub.UserID,
+		TargetURL:            sub.TargetURL,
+		TargetCloud:          sub.TargetCloud.toOAuth(),
+		OriginCloud:          sub.OriginCloud.toOAuth(),
+		Status:               store.LinkedAccountStatus(sub.Status),
+		LastError:            sub.LastError,
+		LastErrorAt:          toTime(sub.LastErrorAt),
+		StatusChangedAt:      toTime(sub.StatusChangedAt),
+		Version:              sub.Version,
+		TargetReauthRequired: sub.TargetReauthRequired,
+	}
+}
+
+func getLinkedAccount(tx *bolt.Tx, linkedAccountID string) (store.LinkedAccount, bool, error) {
+	v := tx.Bucket(resLinkedAccountBucket).Get([]byte(linkedAccountID))
+	if v == nil {
+		return store.LinkedAccount{}, false, nil
+	}
+	var sub dbLinkedAccount
+	if err := json.Decode(v, &sub); err != nil {
+		return store.LinkedAccount{}, false, fmt.Errorf("cannot decode linked account %v: %v", linkedAccountID, err)
+	}
+	return sub.toLinkedAccount(), true, nil
+}
+
+func userIDIndexKey(sub store.LinkedAccount) []byte {
+	return append(indexKey(sub.UserID), sub.ID...)
+}
+
+func putLinkedAccount(tx *bolt.Tx, sub store.LinkedAccount) error {
+	data, err := json.Encode(makeDBLinkedAccount(sub))
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(resLinkedAccountBucket).Put([]byte(sub.ID), data); err != nil {
+		return err
+	}
+	return tx.Bucket(linkedAccountUserIDIndexBucket).Put(userIDIndexKey(sub), nil)
+}
+
+func deleteLinkedAccount(tx *bolt.Tx, sub store.LinkedAccount) error {
+	if err := tx.Bucket(linkedAccountUserIDIndexBucket).Delete(userIDIndexKey(sub)); err != nil {
+		return err
+	}
+	return tx.Bucket(resLinkedAccountBucket).Delete([]byte(sub.ID))
+}
+
+func (s *Store) InsertLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(resLinkedAccountBucket).Get([]byte(sub.ID)) != nil {
+			return fmt.Errorf("cannot insert linked account: duplicit ID %v", sub.ID)
+		}
+		if err := putLinkedAccount(tx, sub); err != nil {
+			return fmt.Errorf("cannot insert linked account: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) UpdateLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		old, ok, err := getLinkedAccount(tx, sub.ID)
+		if err != nil {
+			return fmt.Errorf("cannot update linked account: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("cannot update linked account: not found")
+		}
+		if old.Version != sub.Version {
+			return store.ConflictError{Kind: "linked account", ID: sub.ID}
+		}
+		sub.Version++
+		if err := deleteLinkedAccount(tx, old); err != nil {
+			return fmt.Errorf("cannot update linked account: %v", err)
+		}
+		if err := putLinkedAccount(tx, sub); err != nil {
+			return fmt.Errorf("cannot update linked account: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) RemoveLinkedAccount(ctx context.Context, linkedAccountId string) error {
+	if linkedAccountId == "" {
+		return fmt.Errorf("cannot remove linked account: invalid linkedAccountId")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sub, ok, err := getLinkedAccount(tx, linkedAccountId)
+		if err != nil {
+			return fmt.Errorf("cannot remove linked account: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("cannot remove linked account: not found")
+		}
+		if err := deleteLinkedAccount(tx, sub); err != nil {
+			return fmt.Errorf("cannot remove linked account: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) LoadLinkedAccounts(ctx context.Context, query store.Query, h store.LinkedAccountHandler) error {
+	var linkedAccounts []store.LinkedAccount
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if query.ID == "" && query.UserID != "" {
+			for _, id := range scanIndex(tx, linkedAccountUserIDIndexBucket, indexKey(query.UserID)) {
+				sub, ok, err := getLinkedAccount(tx, id)
+				if err != nil {
+					return err
+				}
+				if ok && (query.LinkedCloudID == "" || query.LinkedCloudID == sub.TargetCloud.LinkedCloudID) {
+					linkedAccounts = append(linkedAccounts, sub)
+				}
+			}
+			return nil
+		}
+		return forEach(tx.Bucket(resLinkedAccountBucket), query.ID, func(v []byte) error {
+			var sub dbLinkedAccount
+			if err := json.Decode(v, &sub); err != nil {
+				return fmt.Errorf("cannot decode linked account: %v", err)
+			}
+			if query.UserID != "" && query.UserID != sub.UserID {
+				return nil
+			}
+			if query.LinkedCloudID != "" && query.LinkedCloudID != sub.TargetCloud.LinkedCloudID {
+				return nil
+			}
+			linkedAccounts = append(linkedAccounts, sub.toLinkedAccount())
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Handle(ctx, &linkedAccountIterator{linkedAccounts: linkedAccounts})
+}
+
+type linkedAccountIterator struct {
+	linkedAccounts []store.LinkedAccount
+}
+
+func (i *linkedAccountIterator) Next(ctx context.Context, s *store.LinkedAccount) bool {
+	if len(i.linkedAccounts) == 0 {
+		return false
+	}
+	*s = i.linkedAccounts[0]
+	i.linkedAccounts = i.linkedAccounts[1:]
+	return true
+}
+
+func (i *linkedAccountIterator) Err() error {
+	return nil
+}
+
+func makeDBTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func toTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
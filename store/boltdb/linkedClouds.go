@@ -0,0 +1,224 @@
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+type dbEndpoint struct {
+	AuthUrl                string
+	TokenUrl               string
+	RevocationUrl          string
+	DeviceAuthorizationUrl string
+	AuthStyle              string
+}
+
+type dbHTTPConfig struct {
+	Timeout            int64
+	RootCAs            string
+	Certificate        string
+	PrivateKey         string
+	Proxy              string
+	InsecureSkipVerify bool
+}
+
+func makeDBHTTPConfig(c store.HTTPConfig) dbHTTPConfig {
+	return dbHTTPConfig{
+		Timeout:            int64(c.Timeout),
+		RootCAs:            c.RootCAs,
+		Certificate:        c.Certificate,
+		PrivateKey:         c.PrivateKey,
+		Proxy:              c.Proxy,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
+
+func (c dbHTTPConfig) toHTTPConfig() store.HTTPConfig {
+	return store.HTTPConfig{
+		Timeout:            time.Duration(c.Timeout),
+		RootCAs:            c.RootCAs,
+		Certificate:        c.Certificate,
+		PrivateKey:         c.PrivateKey,
+		Proxy:              c.Proxy,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
+
+type dbLinkedCloud struct {
+	Id           string
+	Name         string
+	ClientId     string
+	ClientSecret string
+	Scopes       []string
+	Endpoint     dbEndpoint
+	Audience     string
+	AuthParams   map[string]string
+	TokenParams  map[string]string
+	JwksUrl      string
+	Issuer       string
+	PKCE         bool
+	GrantType    string
+	HTTP         dbHTTPConfig
+	Version      uint64
+}
+
+func makeDBLinkedCloud(sub store.LinkedCloud) dbLinkedCloud {
+	return dbLinkedCloud{
+		Id:           sub.ID,
+		Name:         sub.Name,
+		ClientId:     sub.ClientID,
+		ClientSecret: sub.ClientSecret,
+		Scopes:       sub.Scopes,
+		Audience:     sub.Audience,
+		AuthParams:   sub.AuthParams,
+		TokenParams:  sub.TokenParams,
+		JwksUrl:      sub.JwksURL,
+		Issuer:       sub.Issuer,
+		PKCE:         sub.PKCE,
+		GrantType:    string(sub.GrantType),
+		HTTP:         makeDBHTTPConfig(sub.HTTP),
+		Version:      sub.Version,
+		Endpoint: dbEndpoint{
+			AuthUrl:                sub.Endpoint.AuthUrl,
+			TokenUrl:               sub.Endpoint.TokenUrl,
+			RevocationUrl:          sub.Endpoint.RevocationUrl,
+			DeviceAuthorizationUrl: sub.Endpoint.DeviceAuthorizationUrl,
+			AuthStyle:              string(sub.Endpoint.AuthStyle),
+		},
+	}
+}
+
+func (sub dbLinkedCloud) toLinkedCloud() store.LinkedCloud {
+	return store.LinkedCloud{
+		ID:           sub.Id,
+		Name:         sub.Name,
+		ClientID:     sub.ClientId,
+		ClientSecret: sub.ClientSecret,
+		Scopes:       sub.Scopes,
+		Audience:     sub.Audience,
+		AuthParams:   sub.AuthParams,
+		TokenParams:  sub.TokenParams,
+		JwksURL:      sub.JwksUrl,
+		Issuer:       sub.Issuer,
+		PKCE:         sub.PKCE,
+		GrantType:    store.GrantType(sub.GrantType),
+		HTTP:         sub.HTTP.toHTTPConfig(),
+		Version:      sub.Version,
+		Endpoint: store.Endpoint{
+			AuthUrl:                sub.Endpoint.AuthUrl,
+			TokenUrl:               sub.Endpoint.TokenUrl,
+			RevocationUrl:          sub.Endpoint.RevocationUrl,
+			DeviceAuthorizationUrl: sub.Endpoint.DeviceAuthorizationUrl,
+			AuthStyle:              store.AuthStyle(sub.Endpoint.AuthStyle),
+		},
+	}
+}
+
+func putLinkedCloud(tx *bolt.Tx, sub store.LinkedCloud) error {
+	data, err := json.Encode(makeDBLinkedCloud(sub))
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(resLinkedCloudBucket).Put([]byte(sub.ID), data)
+}
+
+func (s *Store) UpdateLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v := tx.Bucket(resLinkedCloudBucket).Get([]byte(sub.ID))
+		if v == nil {
+			return fmt.Errorf("cannot update linked cloud: not found")
+		}
+		var old dbLinkedCloud
+		if err := json.Decode(v, &old); err != nil {
+			return fmt.Errorf("cannot update linked cloud: %v", err)
+		}
+		if old.Version != sub.Version {
+			return store.ConflictError{Kind: "linked cloud", ID: sub.ID}
+		}
+		sub.Version++
+		if err := putLinkedCloud(tx, sub); err != nil {
+			return fmt.Errorf("cannot save linked cloud: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) InsertLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(resLinkedCloudBucket).Get([]byte(sub.ID)) != nil {
+			return fmt.Errorf("cannot save linked cloud: duplicit ID %v", sub.ID)
+		}
+		if err := putLinkedCloud(tx, sub); err != nil {
+			return fmt.Errorf("cannot save linked cloud: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) RemoveLinkedCloud(ctx context.Context, LinkedCloudId string) error {
+	if LinkedCloudId == "" {
+		return fmt.Errorf("cannot remove linked cloud: invalid LinkedCloudId")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(resLinkedCloudBucket)
+		if b.Get([]byte(LinkedCloudId)) == nil {
+			return fmt.Errorf("cannot remove linked cloud: not found")
+		}
+		if err := b.Delete([]byte(LinkedCloudId)); err != nil {
+			return fmt.Errorf("cannot remove linked cloud: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) LoadLinkedClouds(ctx context.Context, query store.Query, h store.LinkedCloudHandler) error {
+	var linkedClouds []store.LinkedCloud
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return forEach(tx.Bucket(resLinkedCloudBucket), query.ID, func(v []byte) error {
+			var sub dbLinkedCloud
+			if err := json.Decode(v, &sub); err != nil {
+				return fmt.Errorf("cannot decode linked cloud: %v", err)
+			}
+			linkedClouds = append(linkedClouds, sub.toLinkedCloud())
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Handle(ctx, &linkedCloudIterator{linkedClouds: linkedClouds})
+}
+
+type linkedCloudIterator struct {
+	linkedClouds []store.LinkedCloud
+}
+
+func (i *linkedCloudIterator) Next(ctx context.Context, s *store.LinkedCloud) bool {
+	if len(i.linkedClouds) == 0 {
+		return false
+	}
+	*s = i.linkedClouds[0]
+	i.linkedClouds = i.linkedClouds[1:]
+	return true
+}
+
+func (i *linkedCloudIterator) Err() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+type dbPendingLink struct {
+	State  string
+	Data   []byte
+	Expiry int64
+}
+
+func decodePendingLink(v []byte) (store.PendingLink, error) {
+	var p dbPendingLink
+	if err := json.Decode(v, &p); err != nil {
+		return store.PendingLink{}, fmt.Errorf("cannot decode pending link: %v", err)
+	}
+	return store.PendingLink{
+		State:  p.State,
+		Data:   p.Data,
+		Expiry: time.Unix(0, p.Expiry),
+	}, nil
+}
+
+// removeExpiredPendingLinks removes pending links whose callback never came.
+func removeExpiredPendingLinks(tx *bolt.Tx) error {
+	var expired [][]byte
+	b := tx.Bucket(pendingLinkBucket)
+	err := b.ForEach(func(k, v []byte) error {
+		p, err := decodePendingLink(v)
+		if err != nil || p.IsExpired() {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) InsertPendingLink(ctx context.Context, link store.PendingLink) error {
+	err := link.Validate()
+	if err != nil {
+		return err
+	}
+	data, err := json.Encode(dbPendingLink{
+		State:  link.State,
+		Data:   link.Data,
+		Expiry: link.Expiry.UnixNano(),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot insert pending link: %v", err)
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := removeExpiredPendingLinks(tx); err != nil {
+			return fmt.Errorf("cannot insert pending link: %v", err)
+		}
+		b := tx.Bucket(pendingLinkBucket)
+		if b.Get([]byte(link.State)) != nil {
+			return fmt.Errorf("cannot insert pending link: duplicit State")
+		}
+		if err := b.Put([]byte(link.State), data); err != nil {
+			return fmt.Errorf("cannot insert pending link: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) PopPendingLink(ctx context.Context, state string) (store.PendingLink, error) {
+	var p store.PendingLink
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(pendingLinkBucket)
+		v := b.Get([]byte(state))
+		if v == nil {
+			return fmt.Errorf("cannot pop pending link: not found")
+		}
+		var err error
+		p, err = decodePendingLink(v)
+		if err != nil {
+			return fmt.Errorf("cannot pop pending link: %v", err)
+		}
+		return b.Delete([]byte(state))
+	})
+	if err != nil {
+		return store.PendingLink{}, err
+	}
+	if p.IsExpired() {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: expired")
+	}
+	return p, nil
+}
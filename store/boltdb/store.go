@@ -0,0 +1,111 @@
+package boltdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Store implements an Store in a single BoltDB file.
+type Store struct {
+	db *bolt.DB
+}
+
+type Config struct {
+	Path    string        `envconfig:"LINKED_STORE_BOLTDB_PATH" default:"openapiConnector.db"`
+	Timeout time.Duration `envconfig:"LINKED_STORE_BOLTDB_TIMEOUT" default:"10s"`
+}
+
+var resLinkedCloudBucket = []byte("LinkedCloud")
+var resLinkedAccountBucket = []byte("linkedAccounts")
+var subscriptionBucket = []byte("Subscription")
+var pendingLinkBucket = []byte("pendingLinks")
+
+// subscription indexes, they are the same as the MongoDB ones
+var typeIndexBucket = []byte("Subscription.type")
+var subscriptionLinkAccountIndexBucket = []byte("Subscription.linkedaccountid")
+var subscriptionDeviceIndexBucket = []byte("Subscription.deviceid_type")
+var subscriptionDeviceHrefIndexBucket = []byte("Subscription.href_deviceid_type")
+
+// linked account indexes
+var linkedAccountUserIDIndexBucket = []byte("linkedAccounts.userid")
+
+var buckets = [][]byte{
+	resLinkedCloudBucket,
+	resLinkedAccountBucket,
+	subscriptionBucket,
+	typeIndexBucket,
+	subscriptionLinkAccountIndexBucket,
+	subscriptionDeviceIndexBucket,
+	subscriptionDeviceHrefIndexBucket,
+	linkedAccountUserIDIndexBucket,
+	pendingLinkBucket,
+}
+
+// NewStore opens or creates the database file.
+func NewStore(ctx context.Context, cfg Config) (*Store, error) {
+	db, err := bolt.Open(cfg.Path, 0600, &bolt.Options{Timeout: cfg.Timeout})
+	if err != nil {
+		return nil, fmt.Errorf("could not open database %v: %v", cfg.Path, err)
+	}
+	return NewStoreWithDB(ctx, db)
+}
+
+// NewStoreWithDB creates a new Store with an opened database.
+func NewStoreWithDB(ctx context.Context, db *bolt.DB) (*Store, error) {
+	if db == nil {
+		return nil, fmt.Errorf("no database")
+	}
+	err := db.Update(ensureBuckets)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("cannot ensure buckets: %v", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func ensureBuckets(tx *bolt.Tx) error {
+	for _, b := range buckets {
+		if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+			return fmt.Errorf("cannot create bucket %s: %v", b, err)
+		}
+	}
+	return nil
+}
+
+// Clear clears the storage.
+func (s *Store) Clear(ctx context.Context) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, b := range buckets {
+			if err := tx.DeleteBucket(b); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+		return ensureBuckets(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("cannot clear: %v", err)
+	}
+	return nil
+}
+
+// Close closes the database file.
+func (s *Store) Close(ctx context.Context) error {
+	return s.db.Close()
+}
+
+// forEach calls f for the value stored under id, or for all values when id is empty.
+func forEach(b *bolt.Bucket, id string, f func(v []byte) error) error {
+	if id != "" {
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		return f(v)
+	}
+	return b.ForEach(func(k, v []byte) error {
+		return f(v)
+	})
+}
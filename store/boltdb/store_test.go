@@ -0,0 +1,75 @@
+package boltdb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		dir, err := ioutil.TempDir("", "boltdb")
+		require.NoError(t, err)
+		ctx := context.Background()
+		s, err := NewStore(ctx, Config{
+			Path:    filepath.Join(dir, "test.db"),
+			Timeout: time.Second,
+		})
+		require.NoError(t, err)
+		return s, func() {
+			s.Close(ctx)
+			os.RemoveAll(dir)
+		}
+	})
+}
+
+func TestStore_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "boltdb")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	cfg := Config{
+		Path:    filepath.Join(dir, "test.db"),
+		Timeout: time.Second,
+	}
+
+	s, err := NewStore(ctx, cfg)
+	require.NoError(t, err)
+	sub := store.Subscription{
+		SubscriptionID:  "0",
+		Type:            store.Type_Resource,
+		LinkedAccountID: "testLinkedAccountID",
+		DeviceID:        "testDeviceID",
+		Href:            "testHref",
+	}
+	_, err = s.FindOrCreateSubscription(ctx, sub)
+	require.NoError(t, err)
+	require.NoError(t, s.Close(ctx))
+
+	s, err = NewStore(ctx, cfg)
+	require.NoError(t, err)
+	defer s.Close(ctx)
+	var h subscriptionsHandler
+	err = s.LoadSubscriptions(ctx, []store.SubscriptionQuery{{Type: store.Type_Resource, DeviceID: "testDeviceID", Href: "testHref"}}, &h)
+	require.NoError(t, err)
+	require.Equal(t, []store.Subscription{sub}, h.subs)
+}
+
+type subscriptionsHandler struct {
+	subs []store.Subscription
+}
+
+func (h *subscriptionsHandler) Handle(ctx context.Context, iter store.SubscriptionIter) (err error) {
+	var sub store.Subscription
+	for iter.Next(ctx, &sub) {
+		h.subs = append(h.subs, sub)
+	}
+	return iter.Err()
+}
@@ -0,0 +1,334 @@
+package boltdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/kit/codec/json"
+	"github.com/go-ocf/openapi-connector/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+type dbSubscription struct {
+	SubscriptionID     string
+	LinkedAccountID    string
+	DeviceID           string
+	Href               string
+	Type               string
+	SigningSecret      string
+	NextSequenceNumber uint64
+}
+
+func makeDBSubscription(sub store.Subscription) dbSubscription {
+	return dbSubscription{
+		SubscriptionID:     sub.SubscriptionID,
+		LinkedAccountID:    sub.LinkedAccountID,
+		DeviceID:           sub.DeviceID,
+		Href:               sub.Href,
+		Type:               string(sub.Type),
+		SigningSecret:      sub.SigningSecret,
+		NextSequenceNumber: sub.NextSequenceNumber,
+	}
+}
+
+func (sub dbSubscription) toSubscription() store.Subscription {
+	return store.Subscription{
+		SubscriptionID:     sub.SubscriptionID,
+		LinkedAccountID:    sub.LinkedAccountID,
+		DeviceID:           sub.DeviceID,
+		Href:               sub.Href,
+		Type:               store.Type(sub.Type),
+		SigningSecret:      sub.SigningSecret,
+		NextSequenceNumber: sub.NextSequenceNumber,
+	}
+}
+
+func indexKey(parts ...string) []byte {
+	var b bytes.Buffer
+	for _, p := range parts {
+		b.WriteString(p)
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
+
+// indexKeys returns the index entries of the subscription by the index bucket.
+func indexKeys(sub store.Subscription) map[string][]byte {
+	keys := map[string][]byte{
+		string(typeIndexBucket):                    append(indexKey(string(sub.Type)), sub.SubscriptionID...),
+		string(subscriptionLinkAccountIndexBucket): append(indexKey(sub.LinkedAccountID), sub.SubscriptionID...),
+	}
+	if sub.DeviceID != "" {
+		keys[string(subscriptionDeviceIndexBucket)] = append(indexKey(sub.DeviceID, string(sub.Type)), sub.SubscriptionID...)
+	}
+	if sub.Href != "" {
+		keys[string(subscriptionDeviceHrefIndexBucket)] = append(indexKey(sub.Href, sub.DeviceID, string(sub.Type)), sub.SubscriptionID...)
+	}
+	return keys
+}
+
+func getSubscription(tx *bolt.Tx, subscriptionID string) (store.Subscription, bool, error) {
+	v := tx.Bucket(subscriptionBucket).Get([]byte(subscriptionID))
+	if v == nil {
+		return store.Subscription{}, false, nil
+	}
+	var sub dbSubscription
+	if err := json.Decode(v, &sub); err != nil {
+		return store.Subscription{}, false, fmt.Errorf("cannot decode subscription %v: %v", subscriptionID, err)
+	}
+	return sub.toSubscription(), true, nil
+}
+
+func putSubscription(tx *bolt.Tx, sub store.Subscription) error {
+	data, err := json.Encode(makeDBSubscription(sub))
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket(subscriptionBucket).Put([]byte(sub.SubscriptionID), data); err != nil {
+		return err
+	}
+	for bucket, key := range indexKeys(sub) {
+		if err := tx.Bucket([]byte(bucket)).Put(key, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func deleteSubscription(tx *bolt.Tx, sub store.Subscription) error {
+	for bucket, key := range indexKeys(sub) {
+		if err := tx.Bucket([]byte(bucket)).Delete(key); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(subscriptionBucket).Delete([]byte(sub.SubscriptionID))
+}
+
+// scanIndex returns IDs of index entries with the prefix.
+func scanIndex(tx *bolt.Tx, bucket []byte, prefix []byte) []string {
+	var ids []string
+	c := tx.Bucket(bucket).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		ids = append(ids, string(k[len(prefix):]))
+	}
+	return ids
+}
+
+// candidateIDs uses the most selective index for the query. Candidates must still be matched by matchSubscription.
+func candidateIDs(tx *bolt.Tx, query store.SubscriptionQuery) []string {
+	switch {
+	case query.SubscriptionID != "":
+		return []string{query.SubscriptionID}
+	case query.Href != "":
+		return scanIndex(tx, subscriptionDeviceHrefIndexBucket, indexKey(query.Href, query.DeviceID, string(query.Type)))
+	case query.DeviceID != "":
+		return scanIndex(tx, subscriptionDeviceIndexBucket, indexKey(query.DeviceID, string(query.Type)))
+	case query.LinkedAccountID != "":
+		return scanIndex(tx, subscriptionLinkAccountIndexBucket, indexKey(query.LinkedAccountID))
+	case query.Type != "":
+		return scanIndex(tx, typeIndexBucket, indexKey(string(query.Type)))
+	}
+	var ids []string
+	tx.Bucket(subscriptionBucket).ForEach(func(k, v []byte) error {
+		ids = append(ids, string(k))
+		return nil
+	})
+	return ids
+}
+
+func matchSubscription(query store.SubscriptionQuery, sub store.Subscription) bool {
+	if query.SubscriptionID != "" && query.SubscriptionID != sub.SubscriptionID {
+		return false
+	}
+	if query.LinkedAccountID != "" && query.LinkedAccountID != sub.LinkedAccountID {
+		return false
+	}
+	if query.Type != "" && query.Type != sub.Type {
+		return false
+	}
+	if query.DeviceID != "" && query.DeviceID != sub.DeviceID {
+		return false
+	}
+	if query.Href != "" && query.Href != sub.Href {
+		return false
+	}
+	return true
+}
+
+func findSubscriptions(tx *bolt.Tx, query store.SubscriptionQuery) ([]store.Subscription, error) {
+	var subs []store.Subscription
+	for _, id := range candidateIDs(tx, query) {
+		sub, ok, err := getSubscription(tx, id)
+		if err != nil {
+			return nil, err
+		}
+		if ok && matchSubscription(query, sub) {
+			subs = append(subs, sub)
+		}
+	}
+	return subs, nil
+}
+
+func (s *Store) LoadSubscriptions(ctx context.Context, queries []store.SubscriptionQuery, h store.SubscriptionHandler) error {
+	for _, query := range queries {
+		err := query.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if len(queries) == 0 {
+		queries = []store.SubscriptionQuery{store.SubscriptionQuery{}}
+	}
+
+	var subs []store.Subscription
+	err := s.db.View(func(tx *bolt.Tx) error {
+		// queries are joined by OR as in MongoDB
+		found := make(map[string]bool)
+		for _, query := range queries {
+			res, err := findSubscriptions(tx, query)
+			if err != nil {
+				return err
+			}
+			for _, sub := range res {
+				if found[sub.SubscriptionID] {
+					continue
+				}
+				found[sub.SubscriptionID] = true
+				subs = append(subs, sub)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return h.Handle(ctx, &subscriptionIterator{subscriptions: subs})
+}
+
+func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscription) (store.Subscription, error) {
+	err := sub.Validate()
+	if err != nil {
+		return store.Subscription{}, err
+	}
+
+	query := store.SubscriptionQuery{
+		LinkedAccountID: sub.LinkedAccountID,
+		Type:            sub.Type,
+	}
+	switch sub.Type {
+	case store.Type_Device:
+		query.DeviceID = sub.DeviceID
+	case store.Type_Resource:
+		query.DeviceID = sub.DeviceID
+		query.Href = sub.Href
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		storedSubs, err := findSubscriptions(tx, query)
+		if err != nil {
+			return err
+		}
+		for _, storedSub := range storedSubs {
+			if storedSub.SubscriptionID != sub.SubscriptionID {
+				return fmt.Errorf("cannot create duplicit subscription of type %v:%v:%v", sub.Type, sub.DeviceID, sub.Href)
+			}
+			return nil
+		}
+		if _, ok, err := getSubscription(tx, sub.SubscriptionID); err != nil || ok {
+			return fmt.Errorf("cannot find and create for device subscription: duplicit SubscriptionID %v", sub.SubscriptionID)
+		}
+		if err := putSubscription(tx, sub); err != nil {
+			return fmt.Errorf("cannot find and create for device subscription: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return store.Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *Store) RemoveSubscriptions(ctx context.Context, query store.SubscriptionQuery) error {
+	if query.DeviceID != "" {
+		return fmt.Errorf("remove by DeviceID is not supported")
+	}
+	if query.Href != "" {
+		return fmt.Errorf("remove by Href is not supported")
+	}
+	if query.Type != "" {
+		return fmt.Errorf("remove by Type is not supported")
+	}
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		subs, err := findSubscriptions(tx, query)
+		if err != nil {
+			return err
+		}
+		for _, sub := range subs {
+			if err := deleteSubscription(tx, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot remove subscriptions: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) UpdateSubscriptionSequenceNumber(ctx context.Context, subscriptionID string, sequenceNumber uint64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sub, ok, err := getSubscription(tx, subscriptionID)
+		if err != nil {
+			return fmt.Errorf("cannot update sequence number: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("cannot update sequence number: subscription %v not found", subscriptionID)
+		}
+		if sequenceNumber < sub.NextSequenceNumber {
+			return store.StaleSequenceNumberError{SubscriptionID: subscriptionID, SequenceNumber: sequenceNumber}
+		}
+		sub.NextSequenceNumber = sequenceNumber + 1
+		if err := putSubscription(tx, sub); err != nil {
+			return fmt.Errorf("cannot update sequence number: %v", err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) UpdateSubscriptionSigningSecret(ctx context.Context, subscriptionID string, signingSecret string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		sub, ok, err := getSubscription(tx, subscriptionID)
+		if err != nil {
+			return fmt.Errorf("cannot update signing secret: %v", err)
+		}
+		if !ok {
+			return fmt.Errorf("cannot update signing secret: subscription %v not found", subscriptionID)
+		}
+		sub.SigningSecret = signingSecret
+		if err := putSubscription(tx, sub); err != nil {
+			return fmt.Errorf("cannot update signing secret: %v", err)
+		}
+		return nil
+	})
+}
+
+type subscriptionIterator struct {
+	subscriptions []store.Subscription
+}
+
+func (i *subscriptionIterator) Next(ctx context.Context, s *store.Subscription) bool {
+	if len(i.subscriptions) == 0 {
+		return false
+	}
+	*s = i.subscriptions[0]
+	i.subscriptions = i.subscriptions[1:]
+	return true
+}
+
+func (i *subscriptionIterator) Err() error {
+	return nil
+}
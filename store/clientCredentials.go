@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// ClientCredentialsToken obtains an access token from the token endpoint of the linked cloud by the client credentials grant.
+func (l LinkedCloud) ClientCredentialsToken(ctx context.Context) (OAuth, error) {
+	if !l.IsClientCredentials() {
+		return OAuth{}, fmt.Errorf("cannot get client credentials token: linked cloud %v uses grant type %v", l.ID, l.GrantType)
+	}
+	client, err := l.HTTPClient()
+	if err != nil {
+		return OAuth{}, err
+	}
+	c := clientcredentials.Config{
+		ClientID:     l.ClientID,
+		ClientSecret: l.ClientSecret,
+		TokenURL:     l.Endpoint.TokenUrl,
+		Scopes:       l.Scopes,
+		AuthStyle:    l.Endpoint.AuthStyle.toOAuth2(),
+	}
+	v := url.Values{}
+	if l.Audience != "" {
+		v.Set("audience", l.Audience)
+	}
+	c.EndpointParams = l.tokenParams(v)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	token, err := c.Token(ctx)
+	if err != nil {
+		return OAuth{}, err
+	}
+	return OAuth{
+		LinkedCloudID: l.ID,
+		AccessToken:   AccessToken(token.AccessToken),
+		Expiry:        token.Expiry,
+		RefreshToken:  token.RefreshToken,
+	}, nil
+}
+
+// refreshTokenSource returns the source of tokens refreshed by the refresh token with TokenParams of the linked cloud.
+// oauth2.Config doesn't support params of the refresh request, so it is requested by clientcredentials.Config, which allows
+// to replace the grant_type by EndpointParams.
+func (l LinkedCloud) refreshTokenSource(ctx context.Context, refreshToken string) oauth2.TokenSource {
+	c := clientcredentials.Config{
+		ClientID:     l.ClientID,
+		ClientSecret: l.ClientSecret,
+		TokenURL:     l.Endpoint.TokenUrl,
+		AuthStyle:    l.Endpoint.AuthStyle.toOAuth2(),
+		EndpointParams: l.tokenParams(url.Values{
+			"grant_type":    []string{"refresh_token"},
+			"refresh_token": []string{refreshToken},
+		}),
+	}
+	return c.TokenSource(ctx)
+}
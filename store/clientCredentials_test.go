@@ -0,0 +1,126 @@
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.PostForm.Get("grant_type") == "refresh_token" {
+			_, _, basicAuth := r.BasicAuth()
+			if basicAuth || r.PostForm.Get("client_secret") != "testClientSecret" || r.PostForm.Get("resource") != "testResource" {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"error":"invalid_client"}`))
+				return
+			}
+			w.Write([]byte(`{"access_token":"refreshedAccessToken","token_type":"bearer","expires_in":3600}`))
+			return
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" || r.PostForm.Get("audience") != "testAudience" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_request"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"newAccessToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer server.Close()
+	expired := store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "oldAccessToken", Expiry: time.Now().Add(-time.Minute)}
+	withRefreshToken := expired
+	withRefreshToken.RefreshToken = "testRefreshToken"
+
+	tests := []struct {
+		name          string
+		token         store.OAuth
+		linkedCloud   store.LinkedCloud
+		want          store.AccessToken
+		wantErr       bool
+		wantPermanent bool
+	}{
+		{
+			name:  "client credentials",
+			token: expired,
+			linkedCloud: store.LinkedCloud{
+				ID:        "testLinkedCloudID",
+				ClientID:  "testClientID",
+				Audience:  "testAudience",
+				GrantType: store.GrantType_CLIENT_CREDENTIALS,
+				Endpoint:  store.Endpoint{TokenUrl: server.URL},
+			},
+			want: "newAccessToken",
+		},
+		{
+			name:  "token params in body",
+			token: withRefreshToken,
+			linkedCloud: store.LinkedCloud{
+				ID:           "testLinkedCloudID",
+				ClientID:     "testClientID",
+				ClientSecret: "testClientSecret",
+				TokenParams:  map[string]string{"resource": "testResource"},
+				Endpoint:     store.Endpoint{TokenUrl: server.URL, AuthStyle: store.AuthStyle_PARAMS},
+			},
+			want: "refreshedAccessToken",
+		},
+		{
+			name:  "token params without auth style",
+			token: withRefreshToken,
+			linkedCloud: store.LinkedCloud{
+				ID:           "testLinkedCloudID",
+				ClientID:     "testClientID",
+				ClientSecret: "testClientSecret",
+				TokenParams:  map[string]string{"resource": "testResource"},
+				// auth style detected by golang.org/x/oauth2 is cached by the token URL
+				Endpoint: store.Endpoint{TokenUrl: server.URL + "/autoDetect"},
+			},
+			want: "refreshedAccessToken",
+		},
+		{
+			name:  "token params in header",
+			token: withRefreshToken,
+			linkedCloud: store.LinkedCloud{
+				ID:           "testLinkedCloudID",
+				ClientID:     "testClientID",
+				ClientSecret: "testClientSecret",
+				TokenParams:  map[string]string{"resource": "testResource"},
+				Endpoint:     store.Endpoint{TokenUrl: server.URL, AuthStyle: store.AuthStyle_HEADER},
+			},
+			wantErr:       true,
+			wantPermanent: true,
+		},
+		{
+			name:  "authorization code without refresh token",
+			token: expired,
+			linkedCloud: store.LinkedCloud{
+				ID:       "testLinkedCloudID",
+				ClientID: "testClientID",
+				Endpoint: store.Endpoint{TokenUrl: server.URL},
+			},
+			wantErr:       true,
+			wantPermanent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.token.Refresh(context.Background(), tt.linkedCloud)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantPermanent, store.IsPermanentRefreshError(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.AccessToken)
+			assert.Equal(t, tt.token.LinkedCloudID, got.LinkedCloudID)
+			assert.Equal(t, tt.token.RefreshToken, got.RefreshToken)
+			assert.True(t, got.Expiry.After(time.Now()))
+		})
+	}
+}
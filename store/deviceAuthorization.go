@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// deviceCodeGrantType is the grant type of the device access token request (RFC 8628 section 3.4).
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+// defaultDeviceInterval is the polling interval in seconds when the authorization server doesn't provide it.
+const defaultDeviceInterval = 5
+
+// DeviceAuthorization is the device authorization response (RFC 8628 section 3.2). The user enters the UserCode
+// at the VerificationURI while the client polls the token endpoint by the DeviceCode.
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	// ExpiresIn is the lifetime of the DeviceCode in seconds.
+	ExpiresIn int64 `json:"expires_in"`
+	// Interval is the minimal time in seconds between polling requests.
+	Interval int64 `json:"interval"`
+}
+
+// DeviceTokenError is the error response of the token endpoint to the device access token request.
+type DeviceTokenError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e DeviceTokenError) Error() string {
+	if e.Description == "" {
+		return fmt.Sprintf("token endpoint returned error %v", e.Code)
+	}
+	return fmt.Sprintf("token endpoint returned error %v: %v", e.Code, e.Description)
+}
+
+// IsAuthorizationPending reports whether the user hasn't finished the authorization yet, so the client continues polling.
+func IsAuthorizationPending(err error) bool {
+	e, ok := err.(DeviceTokenError)
+	return ok && e.Code == "authorization_pending"
+}
+
+// IsSlowDown reports whether the client must increase the polling interval by 5 seconds and continue polling.
+func IsSlowDown(err error) bool {
+	e, ok := err.(DeviceTokenError)
+	return ok && e.Code == "slow_down"
+}
+
+// IsDeviceAuthorizationTerminated reports whether the user denied the authorization or the device code expired,
+// so polling cannot succeed anymore.
+func IsDeviceAuthorizationTerminated(err error) bool {
+	e, ok := err.(DeviceTokenError)
+	return ok && (e.Code == "access_denied" || e.Code == "expired_token")
+}
+
+// postForm posts the form to the endpoint of the linked cloud authenticated by the client credentials. The client secret
+// is sent in the body when the AuthStyle of the linked cloud is params, otherwise by HTTP Basic authentication.
+func (l LinkedCloud) postForm(ctx context.Context, endpoint string, v url.Values) (*http.Response, error) {
+	client, err := l.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	v.Set("client_id", l.ClientID)
+	inParams := l.Endpoint.AuthStyle == AuthStyle_PARAMS
+	if inParams && l.ClientSecret != "" {
+		v.Set("client_secret", l.ClientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(v.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %v", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !inParams && l.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(l.ClientID), url.QueryEscape(l.ClientSecret))
+	}
+	return client.Do(req)
+}
+
+// AuthorizeDevice starts the device authorization grant (RFC 8628) at the device authorization endpoint of the linked cloud.
+// AuthParams of the linked cloud are added to the request.
+func (l LinkedCloud) AuthorizeDevice(ctx context.Context) (DeviceAuthorization, error) {
+	if l.Endpoint.DeviceAuthorizationUrl == "" {
+		return DeviceAuthorization{}, fmt.Errorf("cannot authorize device: linked cloud %v doesn't provide DeviceAuthorizationUrl", l.ID)
+	}
+	v := url.Values{}
+	if len(l.Scopes) > 0 {
+		v.Set("scope", strings.Join(l.Scopes, " "))
+	}
+	if l.Audience != "" {
+		v.Set("audience", l.Audience)
+	}
+	for k, val := range l.AuthParams {
+		v.Set(k, val)
+	}
+	resp, err := l.postForm(ctx, l.Endpoint.DeviceAuthorizationUrl, v)
+	if err != nil {
+		return DeviceAuthorization{}, fmt.Errorf("cannot authorize device: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return DeviceAuthorization{}, fmt.Errorf("cannot authorize device: unexpected statusCode %v: %s", resp.StatusCode, body)
+	}
+	var d DeviceAuthorization
+	err = json.NewDecoder(resp.Body).Decode(&d)
+	if err != nil {
+		return DeviceAuthorization{}, fmt.Errorf("cannot decode device authorization: %v", err)
+	}
+	if d.DeviceCode == "" || d.UserCode == "" || d.VerificationURI == "" {
+		return DeviceAuthorization{}, fmt.Errorf("invalid device authorization: device_code, user_code and verification_uri are required")
+	}
+	if d.Interval <= 0 {
+		d.Interval = defaultDeviceInterval
+	}
+	return d, nil
+}
+
+// DeviceAccessToken polls the token endpoint of the linked cloud for tokens of the device authorization with TokenParams
+// of the linked cloud. DeviceTokenError is returned while the user hasn't finished the authorization or when it was denied.
+// Unlike other token requests, auth styles are not auto-detected, because each request counts as a poll.
+func (l LinkedCloud) DeviceAccessToken(ctx context.Context, deviceCode string) (OAuth, error) {
+	v := url.Values{}
+	v.Set("grant_type", deviceCodeGrantType)
+	v.Set("device_code", deviceCode)
+	resp, err := l.postForm(ctx, l.Endpoint.TokenUrl, l.tokenParams(v))
+	if err != nil {
+		return OAuth{}, fmt.Errorf("cannot get device access token: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return OAuth{}, fmt.Errorf("cannot get device access token: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var e DeviceTokenError
+		if json.Unmarshal(body, &e) == nil && e.Code != "" {
+			return OAuth{}, e
+		}
+		return OAuth{}, fmt.Errorf("cannot get device access token: unexpected statusCode %v: %s", resp.StatusCode, body)
+	}
+	var token struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}
+	err = json.Unmarshal(body, &token)
+	if err != nil {
+		return OAuth{}, fmt.Errorf("cannot decode device access token: %v", err)
+	}
+	if token.AccessToken == "" {
+		return OAuth{}, fmt.Errorf("cannot get device access token: server response missing access_token")
+	}
+	o := OAuth{
+		LinkedCloudID: l.ID,
+		AccessToken:   AccessToken(token.AccessToken),
+		RefreshToken:  token.RefreshToken,
+	}
+	if token.ExpiresIn > 0 {
+		o.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return o, nil
+}
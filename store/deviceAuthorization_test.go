@@ -0,0 +1,79 @@
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceAuthorization(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/device" {
+			w.Write([]byte(`{"device_code":"testDeviceCode","user_code":"testUserCode","verification_uri":"https://test/device"}`))
+			return
+		}
+		switch r.PostForm.Get("device_code") {
+		case "slowDown":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"slow_down"}`))
+		case "denied":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"access_denied"}`))
+		case "expired":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"expired_token"}`))
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"access_token":"testAccessToken","refresh_token":"testRefreshToken","token_type":"bearer","expires_in":3600}`))
+		}
+	}))
+	defer server.Close()
+	l := store.LinkedCloud{
+		ID:       "testLinkedCloudID",
+		ClientID: "testClientID",
+		Endpoint: store.Endpoint{TokenUrl: server.URL + "/token", DeviceAuthorizationUrl: server.URL + "/device"},
+	}
+
+	d, err := l.AuthorizeDevice(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, store.DeviceAuthorization{DeviceCode: "testDeviceCode", UserCode: "testUserCode", VerificationURI: "https://test/device", Interval: 5}, d)
+
+	tests := []struct {
+		name       string
+		deviceCode string
+		wantErr    bool
+		wantSlow   bool
+		wantTerm   bool
+	}{
+		{name: "valid", deviceCode: "testDeviceCode"},
+		{name: "slow down", deviceCode: "slowDown", wantErr: true, wantSlow: true},
+		{name: "denied", deviceCode: "denied", wantErr: true, wantTerm: true},
+		{name: "expired", deviceCode: "expired", wantErr: true, wantTerm: true},
+		{name: "unavailable", deviceCode: "unavailable", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := l.DeviceAccessToken(context.Background(), tt.deviceCode)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantSlow, store.IsSlowDown(err))
+				assert.False(t, store.IsAuthorizationPending(err))
+				assert.Equal(t, tt.wantTerm, store.IsDeviceAuthorizationTerminated(err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, store.AccessToken("testAccessToken"), got.AccessToken)
+			assert.Equal(t, "testRefreshToken", got.RefreshToken)
+			assert.Equal(t, "testLinkedCloudID", got.LinkedCloudID)
+			assert.False(t, got.Expiry.IsZero())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const openIDConfigurationPath = "/.well-known/openid-configuration"
+
+// OpenIDConfiguration is the subset of the OpenID provider metadata (OpenID Connect Discovery 1.0) used by linked clouds.
+type OpenIDConfiguration struct {
+	Issuer                      string `json:"issuer"`
+	AuthorizationEndpoint       string `json:"authorization_endpoint"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	RevocationEndpoint          string `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+	JwksURI                     string `json:"jwks_uri"`
+}
+
+// GetOpenIDConfiguration fetches the openid-configuration of the issuer by the client.
+func GetOpenIDConfiguration(ctx context.Context, client *http.Client, issuer string) (OpenIDConfiguration, error) {
+	issuer = strings.TrimSuffix(issuer, "/")
+	req, err := http.NewRequest(http.MethodGet, issuer+openIDConfigurationPath, nil)
+	if err != nil {
+		return OpenIDConfiguration{}, fmt.Errorf("cannot create openid-configuration request: %v", err)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return OpenIDConfiguration{}, fmt.Errorf("cannot get openid-configuration of %v: %v", issuer, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return OpenIDConfiguration{}, fmt.Errorf("cannot get openid-configuration of %v: unexpected statusCode %v", issuer, resp.StatusCode)
+	}
+	var c OpenIDConfiguration
+	err = json.NewDecoder(resp.Body).Decode(&c)
+	if err != nil {
+		return OpenIDConfiguration{}, fmt.Errorf("cannot decode openid-configuration of %v: %v", issuer, err)
+	}
+	if strings.TrimSuffix(c.Issuer, "/") != issuer {
+		return OpenIDConfiguration{}, fmt.Errorf("invalid openid-configuration of %v: issuer %v doesn't match", issuer, c.Issuer)
+	}
+	if c.AuthorizationEndpoint == "" || c.TokenEndpoint == "" {
+		return OpenIDConfiguration{}, fmt.Errorf("invalid openid-configuration of %v: authorization and token endpoints are required", issuer)
+	}
+	return c, nil
+}
+
+// WithOpenIDConfiguration returns the linked cloud with endpoints of the openid-configuration. Optional endpoints
+// which are not provided by the openid-configuration are kept.
+func (l LinkedCloud) WithOpenIDConfiguration(c OpenIDConfiguration) LinkedCloud {
+	l.Endpoint.AuthUrl = c.AuthorizationEndpoint
+	l.Endpoint.TokenUrl = c.TokenEndpoint
+	if c.RevocationEndpoint != "" {
+		l.Endpoint.RevocationUrl = c.RevocationEndpoint
+	}
+	if c.DeviceAuthorizationEndpoint != "" {
+		l.Endpoint.DeviceAuthorizationUrl = c.DeviceAuthorizationEndpoint
+	}
+	if c.JwksURI != "" {
+		l.JwksURL = c.JwksURI
+	}
+	return l
+}
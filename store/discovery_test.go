@@ -0,0 +1,77 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetOpenIDConfiguration(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/valid/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"%v/valid","authorization_endpoint":"%v/authorize","token_endpoint":"%v/token","revocation_endpoint":"%v/revoke","device_authorization_endpoint":"%v/device","jwks_uri":"%v/jwks"}`,
+				server.URL, server.URL, server.URL, server.URL, server.URL, server.URL)
+		case "/anotherIssuer/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"%v/valid","authorization_endpoint":"%v/authorize","token_endpoint":"%v/token"}`, server.URL, server.URL, server.URL)
+		case "/withoutToken/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":"%v/withoutToken","authorization_endpoint":"%v/authorize"}`, server.URL, server.URL)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		issuer  string
+		want    store.OpenIDConfiguration
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			issuer: server.URL + "/valid/",
+			want: store.OpenIDConfiguration{
+				Issuer:                      server.URL + "/valid",
+				AuthorizationEndpoint:       server.URL + "/authorize",
+				TokenEndpoint:               server.URL + "/token",
+				RevocationEndpoint:          server.URL + "/revoke",
+				DeviceAuthorizationEndpoint: server.URL + "/device",
+				JwksURI:                     server.URL + "/jwks",
+			},
+		},
+		{name: "another issuer", issuer: server.URL + "/anotherIssuer", wantErr: true},
+		{name: "without token endpoint", issuer: server.URL + "/withoutToken", wantErr: true},
+		{name: "not found", issuer: server.URL + "/notFound", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.GetOpenIDConfiguration(context.Background(), http.DefaultClient, tt.issuer)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithOpenIDConfiguration(t *testing.T) {
+	l := store.LinkedCloud{
+		Issuer:   "testIssuer",
+		JwksURL:  "testJwksURL",
+		Endpoint: store.Endpoint{AuthUrl: "oldAuthUrl", TokenUrl: "oldTokenUrl", RevocationUrl: "testRevocationUrl"},
+	}
+	got := l.WithOpenIDConfiguration(store.OpenIDConfiguration{AuthorizationEndpoint: "testAuthUrl", TokenEndpoint: "testTokenUrl"})
+	assert.Equal(t, store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl", RevocationUrl: "testRevocationUrl"}, got.Endpoint)
+	assert.Equal(t, "testJwksURL", got.JwksURL)
+}
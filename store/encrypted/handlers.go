@@ -0,0 +1,97 @@
+package encrypted
+
+import (
+	"context"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+type linkedCloudHandler struct {
+	s *Store
+	h store.LinkedCloudHandler
+}
+
+func (h *linkedCloudHandler) Handle(ctx context.Context, iter store.LinkedCloudIter) error {
+	return h.h.Handle(ctx, &linkedCloudIterator{s: h.s, iter: iter})
+}
+
+type linkedCloudIterator struct {
+	s    *Store
+	iter store.LinkedCloudIter
+	err  error
+}
+
+func (i *linkedCloudIterator) Next(ctx context.Context, l *store.LinkedCloud) bool {
+	if i.err != nil || !i.iter.Next(ctx, l) {
+		return false
+	}
+	*l, i.err = i.s.decryptLinkedCloud(*l)
+	return i.err == nil
+}
+
+func (i *linkedCloudIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Err()
+}
+
+type linkedAccountHandler struct {
+	s *Store
+	h store.LinkedAccountHandler
+}
+
+func (h *linkedAccountHandler) Handle(ctx context.Context, iter store.LinkedAccountIter) error {
+	return h.h.Handle(ctx, &linkedAccountIterator{s: h.s, iter: iter})
+}
+
+type linkedAccountIterator struct {
+	s    *Store
+	iter store.LinkedAccountIter
+	err  error
+}
+
+func (i *linkedAccountIterator) Next(ctx context.Context, l *store.LinkedAccount) bool {
+	if i.err != nil || !i.iter.Next(ctx, l) {
+		return false
+	}
+	*l, i.err = i.s.decryptLinkedAccount(*l)
+	return i.err == nil
+}
+
+func (i *linkedAccountIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Err()
+}
+
+type subscriptionHandler struct {
+	s *Store
+	h store.SubscriptionHandler
+}
+
+func (h *subscriptionHandler) Handle(ctx context.Context, iter store.SubscriptionIter) error {
+	return h.h.Handle(ctx, &subscriptionIterator{s: h.s, iter: iter})
+}
+
+type subscriptionIterator struct {
+	s    *Store
+	iter store.SubscriptionIter
+	err  error
+}
+
+func (i *subscriptionIterator) Next(ctx context.Context, sub *store.Subscription) bool {
+	if i.err != nil || !i.iter.Next(ctx, sub) {
+		return false
+	}
+	*sub, i.err = i.s.decryptSubscription(*sub)
+	return i.err == nil
+}
+
+func (i *subscriptionIterator) Err() error {
+	if i.err != nil {
+		return i.err
+	}
+	return i.iter.Err()
+}
@@ -0,0 +1,157 @@
+package encrypted
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// prefix marks values encrypted by the Keyring. Values without it are
+// considered to be stored in plaintext before encryption was enabled.
+const prefix = "enc:v1:"
+
+type Config struct {
+	// KeyID selects the key used to encrypt new values.
+	KeyID string `envconfig:"LINKED_STORE_ENCRYPTION_KEY_ID"`
+	// Keys maps key IDs to base64 encoded 256-bit keys. Keep retired keys
+	// here until all values have been re-encrypted.
+	Keys map[string]string `envconfig:"LINKED_STORE_ENCRYPTION_KEYS" json:"-"`
+}
+
+// IsEnabled returns true when encryption keys are configured.
+func (c Config) IsEnabled() bool {
+	return len(c.Keys) > 0
+}
+
+// Keyring encrypts values by envelope encryption: every value is encrypted by
+// a random data key which is encrypted by the key encryption key. The ID of
+// the key encryption key is stored with the value for the rotation.
+type Keyring struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// NewKeyring creates keyring from the configuration.
+func NewKeyring(cfg Config) (*Keyring, error) {
+	if cfg.KeyID == "" {
+		return nil, fmt.Errorf("invalid KeyID")
+	}
+	keys := make(map[string]cipher.AEAD, len(cfg.Keys))
+	for id, v := range cfg.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key ID '%v'", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode key %v: %v", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("invalid key %v: 256-bit key is required", id)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %v: %v", id, err)
+		}
+		keys[id] = aead
+	}
+	if _, ok := keys[cfg.KeyID]; !ok {
+		return nil, fmt.Errorf("key %v not found", cfg.KeyID)
+	}
+	return &Keyring{
+		keyID: cfg.KeyID,
+		keys:  keys,
+	}, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(aead cipher.AEAD, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], nil)
+}
+
+// Encrypt encrypts the value by the current key. Empty value stays empty.
+func (k *Keyring) Encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("cannot generate data key: %v", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("cannot create data key cipher: %v", err)
+	}
+	ciphertext, err := seal(dataAEAD, []byte(value))
+	if err != nil {
+		return "", fmt.Errorf("cannot encrypt value: %v", err)
+	}
+	wrappedKey, err := seal(k.keys[k.keyID], dataKey)
+	if err != nil {
+		return "", fmt.Errorf("cannot encrypt data key: %v", err)
+	}
+	return prefix + k.keyID + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+// Decrypt decrypts the value by the key it was encrypted with. Values stored
+// before the encryption was enabled are returned as they are.
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("cannot decrypt value: invalid format")
+	}
+	kek, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("cannot decrypt value: key %v not found", parts[0])
+	}
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: invalid data key: %v", err)
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: invalid ciphertext: %v", err)
+	}
+	dataKey, err := open(kek, wrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt data key: %v", err)
+	}
+	dataAEAD, err := newAEAD(dataKey)
+	if err != nil {
+		return "", fmt.Errorf("cannot create data key cipher: %v", err)
+	}
+	plaintext, err := open(dataAEAD, ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: %v", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsCurrent returns true when the value is empty or encrypted by the current key.
+func (k *Keyring) IsCurrent(value string) bool {
+	return value == "" || strings.HasPrefix(value, prefix+k.keyID+":")
+}
@@ -0,0 +1,92 @@
+package encrypted
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string([]byte{b}), 32)))
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			cfg:  Config{KeyID: "1", Keys: map[string]string{"1": testKey('a')}},
+		},
+		{
+			name:    "missing KeyID",
+			cfg:     Config{Keys: map[string]string{"1": testKey('a')}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown KeyID",
+			cfg:     Config{KeyID: "2", Keys: map[string]string{"1": testKey('a')}},
+			wantErr: true,
+		},
+		{
+			name:    "short key",
+			cfg:     Config{KeyID: "1", Keys: map[string]string{"1": base64.StdEncoding.EncodeToString([]byte("short"))}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid key ID",
+			cfg:     Config{KeyID: "a:b", Keys: map[string]string{"a:b": testKey('a')}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	old, err := NewKeyring(Config{KeyID: "old", Keys: map[string]string{"old": testKey('a')}})
+	require.NoError(t, err)
+	rotated, err := NewKeyring(Config{KeyID: "new", Keys: map[string]string{"old": testKey('a'), "new": testKey('b')}})
+	require.NoError(t, err)
+	retired, err := NewKeyring(Config{KeyID: "new", Keys: map[string]string{"new": testKey('b')}})
+	require.NoError(t, err)
+
+	v, err := old.Encrypt("secret")
+	require.NoError(t, err)
+	assert.NotContains(t, v, "secret")
+	assert.True(t, strings.HasPrefix(v, "enc:v1:old:"))
+	assert.True(t, old.IsCurrent(v))
+	assert.False(t, rotated.IsCurrent(v))
+
+	got, err := rotated.Decrypt(v)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", got)
+
+	_, err = retired.Decrypt(v)
+	assert.Error(t, err)
+
+	got, err = old.Decrypt("plaintext")
+	require.NoError(t, err)
+	assert.Equal(t, "plaintext", got)
+	assert.False(t, old.IsCurrent("plaintext"))
+
+	v, err = old.Encrypt("")
+	require.NoError(t, err)
+	assert.Empty(t, v)
+
+	_, err = old.Decrypt("enc:v1:old:invalid")
+	assert.Error(t, err)
+}
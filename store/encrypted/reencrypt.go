@@ -0,0 +1,118 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+type linkedCloudsHandler struct {
+	linkedClouds []store.LinkedCloud
+}
+
+func (h *linkedCloudsHandler) Handle(ctx context.Context, iter store.LinkedCloudIter) error {
+	var l store.LinkedCloud
+	for iter.Next(ctx, &l) {
+		h.linkedClouds = append(h.linkedClouds, l)
+	}
+	return iter.Err()
+}
+
+type linkedAccountsHandler struct {
+	linkedAccounts []store.LinkedAccount
+}
+
+func (h *linkedAccountsHandler) Handle(ctx context.Context, iter store.LinkedAccountIter) error {
+	var l store.LinkedAccount
+	for iter.Next(ctx, &l) {
+		h.linkedAccounts = append(h.linkedAccounts, l)
+	}
+	return iter.Err()
+}
+
+type subscriptionsHandler struct {
+	subscriptions []store.Subscription
+}
+
+func (h *subscriptionsHandler) Handle(ctx context.Context, iter store.SubscriptionIter) error {
+	var sub store.Subscription
+	for iter.Next(ctx, &sub) {
+		h.subscriptions = append(h.subscriptions, sub)
+	}
+	return iter.Err()
+}
+
+func (s *Store) isCurrentLinkedCloud(l store.LinkedCloud) bool {
+	return s.keyring.IsCurrent(l.ClientSecret) && (l.HTTP.PrivateKey == "" || s.keyring.IsCurrent(l.HTTP.PrivateKey))
+}
+
+func (s *Store) isCurrentOAuth(o store.OAuth) bool {
+	return s.keyring.IsCurrent(string(o.AccessToken)) && s.keyring.IsCurrent(o.RefreshToken)
+}
+
+// Reencrypt encrypts all values which are stored in plaintext or by a retired
+// key with the current key. Afterwards the retired keys can be removed from the
+// configuration.
+//
+// Signing secrets of subscriptions are updated in place, so an interrupted run
+// doesn't lose subscriptions and it can be run again.
+func (s *Store) Reencrypt(ctx context.Context) error {
+	var lcs linkedCloudsHandler
+	err := s.Store.LoadLinkedClouds(ctx, store.Query{}, &lcs)
+	if err != nil {
+		return fmt.Errorf("cannot load linked clouds: %v", err)
+	}
+	for _, l := range lcs.linkedClouds {
+		if s.isCurrentLinkedCloud(l) {
+			continue
+		}
+		l, err = s.decryptLinkedCloud(l)
+		if err != nil {
+			return err
+		}
+		err = s.UpdateLinkedCloud(ctx, l)
+		if err != nil {
+			return fmt.Errorf("cannot reencrypt linked cloud %v: %v", l.ID, err)
+		}
+	}
+
+	var las linkedAccountsHandler
+	err = s.Store.LoadLinkedAccounts(ctx, store.Query{}, &las)
+	if err != nil {
+		return fmt.Errorf("cannot load linked accounts: %v", err)
+	}
+	for _, l := range las.linkedAccounts {
+		if s.isCurrentOAuth(l.TargetCloud) && s.isCurrentOAuth(l.OriginCloud) {
+			continue
+		}
+		l, err = s.decryptLinkedAccount(l)
+		if err != nil {
+			return err
+		}
+		err = s.UpdateLinkedAccount(ctx, l)
+		if err != nil {
+			return fmt.Errorf("cannot reencrypt linked account %v: %v", l.ID, err)
+		}
+	}
+
+	var subs subscriptionsHandler
+	err = s.Store.LoadSubscriptions(ctx, nil, &subs)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %v", err)
+	}
+	for _, sub := range subs.subscriptions {
+		if s.keyring.IsCurrent(sub.SigningSecret) {
+			continue
+		}
+		sub, err = s.decryptSubscription(sub)
+		if err != nil {
+			return err
+		}
+		err = s.UpdateSubscriptionSigningSecret(ctx, sub.SubscriptionID, sub.SigningSecret)
+		if err != nil {
+			return fmt.Errorf("cannot reencrypt subscription %v: %v", sub.SubscriptionID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// Store encrypts secrets of linked clouds, linked accounts, subscriptions and data of pending links
+// before they are passed to the wrapped store and decrypts them on load.
+type Store struct {
+	store.Store
+	keyring *Keyring
+}
+
+// NewStore wraps the store.
+func NewStore(s store.Store, keyring *Keyring) *Store {
+	return &Store{
+		Store:   s,
+		keyring: keyring,
+	}
+}
+
+func (s *Store) encryptLinkedCloud(l store.LinkedCloud) (store.LinkedCloud, error) {
+	var err error
+	l.ClientSecret, err = s.keyring.Encrypt(l.ClientSecret)
+	if err != nil {
+		return l, fmt.Errorf("cannot encrypt ClientSecret of linked cloud %v: %v", l.ID, err)
+	}
+	l.HTTP.PrivateKey, err = s.keyring.Encrypt(l.HTTP.PrivateKey)
+	if err != nil {
+		return l, fmt.Errorf("cannot encrypt PrivateKey of linked cloud %v: %v", l.ID, err)
+	}
+	return l, nil
+}
+
+func (s *Store) decryptLinkedCloud(l store.LinkedCloud) (store.LinkedCloud, error) {
+	var err error
+	l.ClientSecret, err = s.keyring.Decrypt(l.ClientSecret)
+	if err != nil {
+		return l, fmt.Errorf("cannot decrypt ClientSecret of linked cloud %v: %v", l.ID, err)
+	}
+	l.HTTP.PrivateKey, err = s.keyring.Decrypt(l.HTTP.PrivateKey)
+	if err != nil {
+		return l, fmt.Errorf("cannot decrypt PrivateKey of linked cloud %v: %v", l.ID, err)
+	}
+	return l, nil
+}
+
+func (s *Store) encryptOAuth(o store.OAuth) (store.OAuth, error) {
+	accessToken, err := s.keyring.Encrypt(string(o.AccessToken))
+	if err != nil {
+		return o, fmt.Errorf("cannot encrypt AccessToken: %v", err)
+	}
+	o.AccessToken = store.AccessToken(accessToken)
+	o.RefreshToken, err = s.keyring.Encrypt(o.RefreshToken)
+	if err != nil {
+		return o, fmt.Errorf("cannot encrypt RefreshToken: %v", err)
+	}
+	return o, nil
+}
+
+func (s *Store) decryptOAuth(o store.OAuth) (store.OAuth, error) {
+	accessToken, err := s.keyring.Decrypt(string(o.AccessToken))
+	if err != nil {
+		return o, fmt.Errorf("cannot decrypt AccessToken: %v", err)
+	}
+	o.AccessToken = store.AccessToken(accessToken)
+	o.RefreshToken, err = s.keyring.Decrypt(o.RefreshToken)
+	if err != nil {
+		return o, fmt.Errorf("cannot decrypt RefreshToken: %v", err)
+	}
+	return o, nil
+}
+
+func (s *Store) encryptLinkedAccount(l store.LinkedAccount) (store.LinkedAccount, error) {
+	var err error
+	l.TargetCloud, err = s.encryptOAuth(l.TargetCloud)
+	if err != nil {
+		return l, fmt.Errorf("target cloud of linked account %v: %v", l.ID, err)
+	}
+	l.OriginCloud, err = s.encryptOAuth(l.OriginCloud)
+	if err != nil {
+		return l, fmt.Errorf("origin cloud of linked account %v: %v", l.ID, err)
+	}
+	return l, nil
+}
+
+func (s *Store) decryptLinkedAccount(l store.LinkedAccount) (store.LinkedAccount, error) {
+	var err error
+	l.TargetCloud, err = s.decryptOAuth(l.TargetCloud)
+	if err != nil {
+		return l, fmt.Errorf("target cloud of linked account %v: %v", l.ID, err)
+	}
+	l.OriginCloud, err = s.decryptOAuth(l.OriginCloud)
+	if err != nil {
+		return l, fmt.Errorf("origin cloud of linked account %v: %v", l.ID, err)
+	}
+	return l, nil
+}
+
+func (s *Store) encryptSubscription(sub store.Subscription) (store.Subscription, error) {
+	var err error
+	sub.SigningSecret, err = s.keyring.Encrypt(sub.SigningSecret)
+	if err != nil {
+		return sub, fmt.Errorf("cannot encrypt SigningSecret of subscription %v: %v", sub.SubscriptionID, err)
+	}
+	return sub, nil
+}
+
+func (s *Store) decryptSubscription(sub store.Subscription) (store.Subscription, error) {
+	var err error
+	sub.SigningSecret, err = s.keyring.Decrypt(sub.SigningSecret)
+	if err != nil {
+		return sub, fmt.Errorf("cannot decrypt SigningSecret of subscription %v: %v", sub.SubscriptionID, err)
+	}
+	return sub, nil
+}
+
+func (s *Store) UpdateLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	sub, err := s.encryptLinkedCloud(sub)
+	if err != nil {
+		return err
+	}
+	return s.Store.UpdateLinkedCloud(ctx, sub)
+}
+
+func (s *Store) InsertLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	sub, err := s.encryptLinkedCloud(sub)
+	if err != nil {
+		return err
+	}
+	return s.Store.InsertLinkedCloud(ctx, sub)
+}
+
+func (s *Store) LoadLinkedClouds(ctx context.Context, query store.Query, h store.LinkedCloudHandler) error {
+	return s.Store.LoadLinkedClouds(ctx, query, &linkedCloudHandler{s: s, h: h})
+}
+
+func (s *Store) UpdateLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	sub, err := s.encryptLinkedAccount(sub)
+	if err != nil {
+		return err
+	}
+	return s.Store.UpdateLinkedAccount(ctx, sub)
+}
+
+func (s *Store) InsertLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	sub, err := s.encryptLinkedAccount(sub)
+	if err != nil {
+		return err
+	}
+	return s.Store.InsertLinkedAccount(ctx, sub)
+}
+
+func (s *Store) LoadLinkedAccounts(ctx context.Context, query store.Query, h store.LinkedAccountHandler) error {
+	return s.Store.LoadLinkedAccounts(ctx, query, &linkedAccountHandler{s: s, h: h})
+}
+
+func (s *Store) LoadSubscriptions(ctx context.Context, query []store.SubscriptionQuery, h store.SubscriptionHandler) error {
+	return s.Store.LoadSubscriptions(ctx, query, &subscriptionHandler{s: s, h: h})
+}
+
+func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscription) (store.Subscription, error) {
+	encSub, err := s.encryptSubscription(sub)
+	if err != nil {
+		return store.Subscription{}, err
+	}
+	_, err = s.Store.FindOrCreateSubscription(ctx, encSub)
+	if err != nil {
+		return store.Subscription{}, err
+	}
+	return sub, nil
+}
+
+func (s *Store) UpdateSubscriptionSigningSecret(ctx context.Context, subscriptionID string, signingSecret string) error {
+	encSigningSecret, err := s.keyring.Encrypt(signingSecret)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt SigningSecret of subscription %v: %v", subscriptionID, err)
+	}
+	return s.Store.UpdateSubscriptionSigningSecret(ctx, subscriptionID, encSigningSecret)
+}
+
+func (s *Store) InsertPendingLink(ctx context.Context, link store.PendingLink) error {
+	data, err := s.keyring.Encrypt(string(link.Data))
+	if err != nil {
+		return fmt.Errorf("cannot encrypt pending link: %v", err)
+	}
+	link.Data = []byte(data)
+	return s.Store.InsertPendingLink(ctx, link)
+}
+
+func (s *Store) PopPendingLink(ctx context.Context, state string) (store.PendingLink, error) {
+	link, err := s.Store.PopPendingLink(ctx, state)
+	if err != nil {
+		return link, err
+	}
+	data, err := s.keyring.Decrypt(string(link.Data))
+	if err != nil {
+		return store.PendingLink{}, fmt.Errorf("cannot decrypt pending link: %v", err)
+	}
+	link.Data = []byte(data)
+	return link, nil
+}
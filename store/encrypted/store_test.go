@@ -0,0 +1,162 @@
+package encrypted
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/go-ocf/openapi-connector/store/storetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyring(t *testing.T, keyID string) *Keyring {
+	k, err := NewKeyring(Config{KeyID: keyID, Keys: map[string]string{"1": testKey('a'), "2": testKey('b')}})
+	require.NoError(t, err)
+	return k
+}
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		s := inmemory.NewStore()
+		return NewStore(s, newTestKeyring(t, "1")), func() { s.Close(context.Background()) }
+	})
+}
+
+func isEncrypted(v string) bool {
+	return strings.HasPrefix(v, prefix)
+}
+
+func TestStore_Reencrypt(t *testing.T) {
+	ctx := context.Background()
+	raw := inmemory.NewStore()
+
+	// plaintext values stored before encryption was enabled
+	require.NoError(t, raw.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl"},
+		HTTP:         store.HTTPConfig{PrivateKey: "testPrivateKey"},
+	}))
+	s := NewStore(raw, newTestKeyring(t, "1"))
+	require.NoError(t, s.InsertLinkedAccount(ctx, store.LinkedAccount{
+		ID:          "testID",
+		TargetURL:   "testTargetURL",
+		TargetCloud: store.OAuth{LinkedCloudID: "testID", AccessToken: "testAccessToken", RefreshToken: "testRefreshToken"},
+		OriginCloud: store.OAuth{AccessToken: "testOriginAccessToken"},
+	}))
+	_, err := s.FindOrCreateSubscription(ctx, store.Subscription{
+		SubscriptionID:  "testSubscriptionID",
+		Type:            store.Type_Devices,
+		LinkedAccountID: "testID",
+		SigningSecret:   "testSigningSecret",
+	})
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateSubscriptionSequenceNumber(ctx, "testSubscriptionID", 4))
+
+	var rawAccounts linkedAccountsHandler
+	require.NoError(t, raw.LoadLinkedAccounts(ctx, store.Query{}, &rawAccounts))
+	require.Len(t, rawAccounts.linkedAccounts, 1)
+	assert.True(t, isEncrypted(string(rawAccounts.linkedAccounts[0].TargetCloud.AccessToken)))
+	assert.True(t, isEncrypted(rawAccounts.linkedAccounts[0].TargetCloud.RefreshToken))
+	assert.True(t, isEncrypted(string(rawAccounts.linkedAccounts[0].OriginCloud.AccessToken)))
+	assert.Empty(t, rawAccounts.linkedAccounts[0].OriginCloud.RefreshToken)
+
+	// rotate the key
+	s = NewStore(raw, newTestKeyring(t, "2"))
+	require.NoError(t, s.Reencrypt(ctx))
+
+	var rawClouds linkedCloudsHandler
+	require.NoError(t, raw.LoadLinkedClouds(ctx, store.Query{}, &rawClouds))
+	require.Len(t, rawClouds.linkedClouds, 1)
+	assert.True(t, strings.HasPrefix(rawClouds.linkedClouds[0].ClientSecret, prefix+"2:"))
+	assert.True(t, strings.HasPrefix(rawClouds.linkedClouds[0].HTTP.PrivateKey, prefix+"2:"))
+
+	rawAccounts = linkedAccountsHandler{}
+	require.NoError(t, raw.LoadLinkedAccounts(ctx, store.Query{}, &rawAccounts))
+	require.Len(t, rawAccounts.linkedAccounts, 1)
+	assert.True(t, strings.HasPrefix(rawAccounts.linkedAccounts[0].TargetCloud.RefreshToken, prefix+"2:"))
+
+	var rawSubs subscriptionsHandler
+	require.NoError(t, raw.LoadSubscriptions(ctx, nil, &rawSubs))
+	require.Len(t, rawSubs.subscriptions, 1)
+	assert.True(t, strings.HasPrefix(rawSubs.subscriptions[0].SigningSecret, prefix+"2:"))
+	assert.Equal(t, uint64(5), rawSubs.subscriptions[0].NextSequenceNumber)
+
+	// the old key is not needed anymore
+	k, err := NewKeyring(Config{KeyID: "2", Keys: map[string]string{"2": testKey('b')}})
+	require.NoError(t, err)
+	s = NewStore(raw, k)
+	var clouds linkedCloudsHandler
+	require.NoError(t, s.LoadLinkedClouds(ctx, store.Query{}, &clouds))
+	assert.Equal(t, "testClientSecret", clouds.linkedClouds[0].ClientSecret)
+	assert.Equal(t, "testPrivateKey", clouds.linkedClouds[0].HTTP.PrivateKey)
+	var accounts linkedAccountsHandler
+	require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{}, &accounts))
+	assert.Equal(t, "testRefreshToken", accounts.linkedAccounts[0].TargetCloud.RefreshToken)
+	var subs subscriptionsHandler
+	require.NoError(t, s.LoadSubscriptions(ctx, nil, &subs))
+	assert.Equal(t, "testSigningSecret", subs.subscriptions[0].SigningSecret)
+}
+
+func TestStore_ReencryptPrivateKey(t *testing.T) {
+	ctx := context.Background()
+	raw := inmemory.NewStore()
+	old := newTestKeyring(t, "1")
+	current := newTestKeyring(t, "2")
+
+	// the private key was added while the retired key was current
+	clientSecret, err := current.Encrypt("testClientSecret")
+	require.NoError(t, err)
+	privateKey, err := old.Encrypt("testPrivateKey")
+	require.NoError(t, err)
+	require.NoError(t, raw.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testID",
+		ClientID:     "testClientID",
+		ClientSecret: clientSecret,
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: "testAuthUrl", TokenUrl: "testTokenUrl"},
+		HTTP:         store.HTTPConfig{PrivateKey: privateKey},
+	}))
+
+	s := NewStore(raw, current)
+	require.NoError(t, s.Reencrypt(ctx))
+
+	var rawClouds linkedCloudsHandler
+	require.NoError(t, raw.LoadLinkedClouds(ctx, store.Query{}, &rawClouds))
+	require.Len(t, rawClouds.linkedClouds, 1)
+	assert.True(t, strings.HasPrefix(rawClouds.linkedClouds[0].ClientSecret, prefix+"2:"))
+	assert.True(t, strings.HasPrefix(rawClouds.linkedClouds[0].HTTP.PrivateKey, prefix+"2:"))
+
+	k, err := NewKeyring(Config{KeyID: "2", Keys: map[string]string{"2": testKey('b')}})
+	require.NoError(t, err)
+	var clouds linkedCloudsHandler
+	require.NoError(t, NewStore(raw, k).LoadLinkedClouds(ctx, store.Query{}, &clouds))
+	assert.Equal(t, "testClientSecret", clouds.linkedClouds[0].ClientSecret)
+	assert.Equal(t, "testPrivateKey", clouds.linkedClouds[0].HTTP.PrivateKey)
+}
+
+func TestStore_PendingLink(t *testing.T) {
+	ctx := context.Background()
+	raw := inmemory.NewStore()
+	s := NewStore(raw, newTestKeyring(t, "1"))
+
+	require.NoError(t, s.InsertPendingLink(ctx, store.PendingLink{
+		State:  "testState",
+		Data:   []byte("testData"),
+		Expiry: time.Now().Add(time.Hour),
+	}))
+	link, err := raw.PopPendingLink(ctx, "testState")
+	require.NoError(t, err)
+	assert.True(t, isEncrypted(string(link.Data)))
+
+	require.NoError(t, raw.InsertPendingLink(ctx, link))
+	link, err = s.PopPendingLink(ctx, "testState")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("testData"), link.Data)
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// HTTPConfig configures the HTTP client used for requests to the linked cloud.
+type HTTPConfig struct {
+	// Timeout of a request. Requests don't time out when it is zero.
+	Timeout time.Duration `json:"Timeout" envconfig:"TIMEOUT"`
+	// RootCAs PEM encoded certificates which verify the linked cloud instead of the system roots.
+	RootCAs string `json:"RootCAs" envconfig:"ROOT_CAS"`
+	// Certificate PEM encoded client certificate for mutual TLS. It requires the PrivateKey.
+	Certificate string `json:"Certificate" envconfig:"CERTIFICATE"`
+	// PrivateKey PEM encoded private key of the client certificate.
+	PrivateKey string `json:"PrivateKey" envconfig:"PRIVATE_KEY"`
+	// Proxy URL. The proxy is taken from the environment when it is empty.
+	Proxy string `json:"Proxy" envconfig:"PROXY"`
+	// InsecureSkipVerify disables verification of the linked cloud certificate. Use it for testing only.
+	InsecureSkipVerify bool `json:"InsecureSkipVerify" envconfig:"INSECURE_SKIP_VERIFY"`
+}
+
+// httpClients caches clients of linked clouds, so connections are reused.
+var httpClients = struct {
+	sync.Mutex
+	clients map[httpClientKey]cachedHTTPClient
+}{clients: make(map[httpClientKey]cachedHTTPClient)}
+
+// httpClientKey identifies the client of a linked cloud by its ID. Linked clouds without ID, e.g. the origin cloud
+// from the configuration, are identified by their HTTP configuration, so they don't share the client with each other.
+type httpClientKey struct {
+	linkedCloudID string
+	cfg           HTTPConfig
+}
+
+func makeHTTPClientKey(l LinkedCloud) httpClientKey {
+	if l.ID == "" {
+		return httpClientKey{cfg: l.HTTP}
+	}
+	return httpClientKey{linkedCloudID: l.ID}
+}
+
+type cachedHTTPClient struct {
+	cfg    HTTPConfig
+	client *http.Client
+}
+
+// NewHTTPClient returns a new client configured by the cfg.
+func NewHTTPClient(cfg HTTPConfig) (*http.Client, error) {
+	tlsConfig := tls.Config{
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.RootCAs != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(cfg.RootCAs)) {
+			return nil, fmt.Errorf("cannot create http client: invalid RootCAs")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.Certificate != "" || cfg.PrivateKey != "" {
+		cert, err := tls.X509KeyPair([]byte(cfg.Certificate), []byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("cannot create http client: invalid client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("cannot create http client: invalid Proxy: %v", err)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tlsConfig
+	transport.Proxy = proxy
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}, nil
+}
+
+// HTTPClient returns the client for requests to the linked cloud. The client is shared by calls with the same linked cloud,
+// it is replaced when the HTTP configuration of the linked cloud changes. Linked clouds without ID share the client
+// only with the same HTTP configuration.
+func (l LinkedCloud) HTTPClient() (*http.Client, error) {
+	key := makeHTTPClientKey(l)
+	httpClients.Lock()
+	defer httpClients.Unlock()
+	if c, ok := httpClients.clients[key]; ok {
+		if c.cfg == l.HTTP {
+			return c.client, nil
+		}
+		c.client.CloseIdleConnections()
+		delete(httpClients.clients, key)
+	}
+	c, err := NewHTTPClient(l.HTTP)
+	if err != nil {
+		return nil, fmt.Errorf("linked cloud %v: %v", l.ID, err)
+	}
+	httpClients.clients[key] = cachedHTTPClient{cfg: l.HTTP, client: c}
+	return c, nil
+}
+
+// CloseHTTPClient closes idle connections of the client of the linked cloud and forgets it. Call it when the linked cloud is removed.
+func CloseHTTPClient(linkedCloudID string) {
+	httpClients.Lock()
+	defer httpClients.Unlock()
+	key := httpClientKey{linkedCloudID: linkedCloudID}
+	if c, ok := httpClients.clients[key]; ok {
+		c.client.CloseIdleConnections()
+		delete(httpClients.clients, key)
+	}
+}
+
+// LoadTargetCloud loads the linked cloud of the target cloud of the linked account.
+func (l LinkedAccount) LoadTargetCloud(ctx context.Context, s Store) (LinkedCloud, error) {
+	var h LinkedCloudsHandler
+	err := s.LoadLinkedClouds(ctx, Query{ID: l.TargetCloud.LinkedCloudID}, &h)
+	if err != nil {
+		return LinkedCloud{}, fmt.Errorf("cannot load linked cloud %v: %v", l.TargetCloud.LinkedCloudID, err)
+	}
+	if len(h.LinkedClouds) != 1 {
+		return LinkedCloud{}, fmt.Errorf("cannot load linked cloud %v: not found", l.TargetCloud.LinkedCloudID)
+	}
+	return h.LinkedClouds[0], nil
+}
+
+// TargetHTTPClient returns the client for requests to the target cloud of the linked account.
+func (l LinkedAccount) TargetHTTPClient(ctx context.Context, s Store) (*http.Client, error) {
+	c, err := l.LoadTargetCloud(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return c.HTTPClient()
+}
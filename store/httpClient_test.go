@@ -0,0 +1,100 @@
+package store_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	rootCAs := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	tests := []struct {
+		name       string
+		cfg        store.HTTPConfig
+		wantErr    bool
+		wantGetErr bool
+	}{
+		{name: "system roots", cfg: store.HTTPConfig{Timeout: time.Second}, wantGetErr: true},
+		{name: "root CAs", cfg: store.HTTPConfig{Timeout: time.Second, RootCAs: rootCAs}},
+		{name: "insecure", cfg: store.HTTPConfig{Timeout: time.Second, InsecureSkipVerify: true}},
+		{name: "invalid root CAs", cfg: store.HTTPConfig{RootCAs: "invalid"}, wantErr: true},
+		{name: "certificate without private key", cfg: store.HTTPConfig{Certificate: rootCAs}, wantErr: true},
+		{name: "invalid proxy", cfg: store.HTTPConfig{Proxy: "://invalid"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := store.NewHTTPClient(tt.cfg)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.cfg.Timeout, c.Timeout)
+
+			resp, err := c.Get(server.URL)
+			if tt.wantGetErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestLinkedCloud_HTTPClient(t *testing.T) {
+	l := store.LinkedCloud{ID: "testHTTPClientID", HTTP: store.HTTPConfig{Timeout: time.Second}}
+	c, err := l.HTTPClient()
+	require.NoError(t, err)
+	same, err := l.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, c == same)
+
+	other, err := store.LinkedCloud{ID: "testOtherHTTPClientID", HTTP: l.HTTP}.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, c != other)
+
+	// the client is replaced when the configuration changes
+	l.HTTP.Timeout = 2 * time.Second
+	changed, err := l.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, c != changed)
+	assert.Equal(t, l.HTTP.Timeout, changed.Timeout)
+	same, err = l.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, changed == same)
+
+	store.CloseHTTPClient(l.ID)
+	removed, err := l.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, changed != removed)
+}
+
+func TestLinkedCloud_HTTPClientWithoutID(t *testing.T) {
+	origin := store.LinkedCloud{HTTP: store.HTTPConfig{Timeout: time.Second}}
+	other := store.LinkedCloud{HTTP: store.HTTPConfig{Timeout: 2 * time.Second}}
+
+	c, err := origin.HTTPClient()
+	require.NoError(t, err)
+	otherClient, err := other.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, c != otherClient)
+	assert.Equal(t, other.HTTP.Timeout, otherClient.Timeout)
+
+	// the client of the linked cloud without ID is not replaced by other linked clouds without ID
+	same, err := origin.HTTPClient()
+	require.NoError(t, err)
+	assert.True(t, c == same)
+}
@@ -0,0 +1,100 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+func (s *Store) InsertLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.linkedAccounts[sub.ID]; ok {
+		return fmt.Errorf("cannot insert linked account: duplicit ID %v", sub.ID)
+	}
+	s.linkedAccounts[sub.ID] = sub
+	s.linkedAccountIDs = append(s.linkedAccountIDs, sub.ID)
+	return nil
+}
+
+func (s *Store) UpdateLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old, ok := s.linkedAccounts[sub.ID]
+	if !ok {
+		return fmt.Errorf("cannot update linked account: not found")
+	}
+	if old.Version != sub.Version {
+		return store.ConflictError{Kind: "linked account", ID: sub.ID}
+	}
+	sub.Version++
+	s.linkedAccounts[sub.ID] = sub
+	return nil
+}
+
+func (s *Store) RemoveLinkedAccount(ctx context.Context, linkedAccountId string) error {
+	if linkedAccountId == "" {
+		return fmt.Errorf("cannot remove linked account: invalid linkedAccountId")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.linkedAccounts[linkedAccountId]; !ok {
+		return fmt.Errorf("cannot remove linked account: not found")
+	}
+	delete(s.linkedAccounts, linkedAccountId)
+	s.linkedAccountIDs = removeID(s.linkedAccountIDs, linkedAccountId)
+	return nil
+}
+
+func (s *Store) LoadLinkedAccounts(ctx context.Context, query store.Query, h store.LinkedAccountHandler) error {
+	s.lock.Lock()
+	linkedAccounts := make([]store.LinkedAccount, 0, len(s.linkedAccountIDs))
+	for _, id := range s.linkedAccountIDs {
+		if query.ID != "" && query.ID != id {
+			continue
+		}
+		l := s.linkedAccounts[id]
+		if query.UserID != "" && query.UserID != l.UserID {
+			continue
+		}
+		if query.LinkedCloudID != "" && query.LinkedCloudID != l.TargetCloud.LinkedCloudID {
+			continue
+		}
+		linkedAccounts = append(linkedAccounts, l)
+	}
+	s.lock.Unlock()
+
+	return h.Handle(ctx, &linkedAccountIterator{linkedAccounts: linkedAccounts})
+}
+
+type linkedAccountIterator struct {
+	linkedAccounts []store.LinkedAccount
+}
+
+func (i *linkedAccountIterator) Next(ctx context.Context, s *store.LinkedAccount) bool {
+	if len(i.linkedAccounts) == 0 {
+		return false
+	}
+	*s = i.linkedAccounts[0]
+	i.linkedAccounts = i.linkedAccounts[1:]
+	return true
+}
+
+func (i *linkedAccountIterator) Err() error {
+	return nil
+}
@@ -0,0 +1,100 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+func copyLinkedCloud(l store.LinkedCloud) store.LinkedCloud {
+	if l.Scopes != nil {
+		l.Scopes = append([]string(nil), l.Scopes...)
+	}
+	return l
+}
+
+func (s *Store) UpdateLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	old, ok := s.linkedClouds[sub.ID]
+	if !ok {
+		return fmt.Errorf("cannot update linked cloud: not found")
+	}
+	if old.Version != sub.Version {
+		return store.ConflictError{Kind: "linked cloud", ID: sub.ID}
+	}
+	sub.Version++
+	s.linkedClouds[sub.ID] = copyLinkedCloud(sub)
+	return nil
+}
+
+func (s *Store) InsertLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
+	err := sub.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.linkedClouds[sub.ID]; ok {
+		return fmt.Errorf("cannot save linked cloud: duplicit ID %v", sub.ID)
+	}
+	s.linkedClouds[sub.ID] = copyLinkedCloud(sub)
+	s.linkedCloudIDs = append(s.linkedCloudIDs, sub.ID)
+	return nil
+}
+
+func (s *Store) RemoveLinkedCloud(ctx context.Context, LinkedCloudId string) error {
+	if LinkedCloudId == "" {
+		return fmt.Errorf("cannot remove linked cloud: invalid LinkedCloudId")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.linkedClouds[LinkedCloudId]; !ok {
+		return fmt.Errorf("cannot remove linked cloud: not found")
+	}
+	delete(s.linkedClouds, LinkedCloudId)
+	s.linkedCloudIDs = removeID(s.linkedCloudIDs, LinkedCloudId)
+	return nil
+}
+
+func (s *Store) LoadLinkedClouds(ctx context.Context, query store.Query, h store.LinkedCloudHandler) error {
+	s.lock.Lock()
+	linkedClouds := make([]store.LinkedCloud, 0, len(s.linkedCloudIDs))
+	for _, id := range s.linkedCloudIDs {
+		if query.ID != "" && query.ID != id {
+			continue
+		}
+		linkedClouds = append(linkedClouds, copyLinkedCloud(s.linkedClouds[id]))
+	}
+	s.lock.Unlock()
+
+	return h.Handle(ctx, &linkedCloudIterator{linkedClouds: linkedClouds})
+}
+
+type linkedCloudIterator struct {
+	linkedClouds []store.LinkedCloud
+}
+
+func (i *linkedCloudIterator) Next(ctx context.Context, s *store.LinkedCloud) bool {
+	if len(i.linkedClouds) == 0 {
+		return false
+	}
+	*s = i.linkedClouds[0]
+	i.linkedClouds = i.linkedClouds[1:]
+	return true
+}
+
+func (i *linkedCloudIterator) Err() error {
+	return nil
+}
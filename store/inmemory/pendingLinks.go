@@ -0,0 +1,55 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+func copyPendingLink(p store.PendingLink) store.PendingLink {
+	if p.Data != nil {
+		p.Data = append([]byte(nil), p.Data...)
+	}
+	return p
+}
+
+func (s *Store) removeExpiredPendingLinksLocked() {
+	for state, p := range s.pendingLinks {
+		if p.IsExpired() {
+			delete(s.pendingLinks, state)
+		}
+	}
+}
+
+func (s *Store) InsertPendingLink(ctx context.Context, link store.PendingLink) error {
+	err := link.Validate()
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.removeExpiredPendingLinksLocked()
+	if _, ok := s.pendingLinks[link.State]; ok {
+		return fmt.Errorf("cannot insert pending link: duplicit State")
+	}
+	s.pendingLinks[link.State] = copyPendingLink(link)
+	return nil
+}
+
+func (s *Store) PopPendingLink(ctx context.Context, state string) (store.PendingLink, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	p, ok := s.pendingLinks[state]
+	if !ok {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: not found")
+	}
+	delete(s.pendingLinks, state)
+	if p.IsExpired() {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: expired")
+	}
+	return p, nil
+}
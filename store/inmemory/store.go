@@ -0,0 +1,63 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+// Store implements an Store in memory. It is intended for tests and
+// embedded deployments where the data doesn't need to survive a restart.
+type Store struct {
+	lock sync.Mutex
+
+	linkedClouds   map[string]store.LinkedCloud
+	linkedAccounts map[string]store.LinkedAccount
+	subscriptions  map[string]store.Subscription
+	pendingLinks   map[string]store.PendingLink
+
+	// keep insertion order so results are stable as with MongoDB natural order
+	linkedCloudIDs   []string
+	linkedAccountIDs []string
+	subscriptionIDs  []string
+}
+
+// NewStore creates a new empty Store.
+func NewStore() *Store {
+	s := &Store{}
+	s.clearLocked()
+	return s
+}
+
+func (s *Store) clearLocked() {
+	s.linkedClouds = make(map[string]store.LinkedCloud)
+	s.linkedAccounts = make(map[string]store.LinkedAccount)
+	s.subscriptions = make(map[string]store.Subscription)
+	s.pendingLinks = make(map[string]store.PendingLink)
+	s.linkedCloudIDs = nil
+	s.linkedAccountIDs = nil
+	s.subscriptionIDs = nil
+}
+
+// Clear clears the storage.
+func (s *Store) Clear(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.clearLocked()
+	return nil
+}
+
+// Close releases the storage.
+func (s *Store) Close(ctx context.Context) error {
+	return s.Clear(ctx)
+}
+
+func removeID(ids []string, id string) []string {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,16 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		s := NewStore()
+		return s, func() { s.Close(context.Background()) }
+	})
+}
@@ -0,0 +1,169 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-ocf/openapi-connector/store"
+)
+
+func matchSubscription(query store.SubscriptionQuery, sub store.Subscription) bool {
+	if query.SubscriptionID != "" && query.SubscriptionID != sub.SubscriptionID {
+		return false
+	}
+	if query.LinkedAccountID != "" && query.LinkedAccountID != sub.LinkedAccountID {
+		return false
+	}
+	if query.Type != "" && query.Type != sub.Type {
+		return false
+	}
+	if query.DeviceID != "" && query.DeviceID != sub.DeviceID {
+		return false
+	}
+	if query.Href != "" && query.Href != sub.Href {
+		return false
+	}
+	return true
+}
+
+func (s *Store) LoadSubscriptions(ctx context.Context, queries []store.SubscriptionQuery, h store.SubscriptionHandler) error {
+	for _, query := range queries {
+		err := query.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	subs := make([]store.Subscription, 0, len(s.subscriptionIDs))
+	for _, id := range s.subscriptionIDs {
+		sub := s.subscriptions[id]
+		if len(queries) == 0 {
+			subs = append(subs, sub)
+			continue
+		}
+		// queries are joined by OR as in MongoDB
+		for _, query := range queries {
+			if matchSubscription(query, sub) {
+				subs = append(subs, sub)
+				break
+			}
+		}
+	}
+	s.lock.Unlock()
+
+	return h.Handle(ctx, &subscriptionIterator{subscriptions: subs})
+}
+
+// sameSubscriptionKey reports whether a and b subscribe the same linked account to the same target.
+func sameSubscriptionKey(a, b store.Subscription) bool {
+	if a.LinkedAccountID != b.LinkedAccountID || a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case store.Type_Device:
+		return a.DeviceID == b.DeviceID
+	case store.Type_Resource:
+		return a.DeviceID == b.DeviceID && a.Href == b.Href
+	}
+	return true
+}
+
+func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscription) (store.Subscription, error) {
+	err := sub.Validate()
+	if err != nil {
+		return store.Subscription{}, err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, id := range s.subscriptionIDs {
+		storedSub := s.subscriptions[id]
+		if !sameSubscriptionKey(storedSub, sub) {
+			continue
+		}
+		if storedSub.SubscriptionID != sub.SubscriptionID {
+			return store.Subscription{}, fmt.Errorf("cannot create duplicit subscription of type %v:%v:%v", sub.Type, sub.DeviceID, sub.Href)
+		}
+		return sub, nil
+	}
+	if _, ok := s.subscriptions[sub.SubscriptionID]; ok {
+		return store.Subscription{}, fmt.Errorf("cannot find and create for device subscription: duplicit SubscriptionID %v", sub.SubscriptionID)
+	}
+	s.subscriptions[sub.SubscriptionID] = sub
+	s.subscriptionIDs = append(s.subscriptionIDs, sub.SubscriptionID)
+	return sub, nil
+}
+
+func (s *Store) RemoveSubscriptions(ctx context.Context, query store.SubscriptionQuery) error {
+	if query.DeviceID != "" {
+		return fmt.Errorf("remove by DeviceID is not supported")
+	}
+	if query.Href != "" {
+		return fmt.Errorf("remove by Href is not supported")
+	}
+	if query.Type != "" {
+		return fmt.Errorf("remove by Type is not supported")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids := make([]string, 0, len(s.subscriptionIDs))
+	for _, id := range s.subscriptionIDs {
+		if matchSubscription(query, s.subscriptions[id]) {
+			delete(s.subscriptions, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	s.subscriptionIDs = ids
+	return nil
+}
+
+func (s *Store) UpdateSubscriptionSequenceNumber(ctx context.Context, subscriptionID string, sequenceNumber uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sub, ok := s.subscriptions[subscriptionID]
+	if !ok {
+		return fmt.Errorf("cannot update sequence number: subscription %v not found", subscriptionID)
+	}
+	if sequenceNumber < sub.NextSequenceNumber {
+		return store.StaleSequenceNumberError{SubscriptionID: subscriptionID, SequenceNumber: sequenceNumber}
+	}
+	sub.NextSequenceNumber = sequenceNumber + 1
+	s.subscriptions[subscriptionID] = sub
+	return nil
+}
+
+func (s *Store) UpdateSubscriptionSigningSecret(ctx context.Context, subscriptionID string, signingSecret string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	sub, ok := s.subscriptions[subscriptionID]
+	if !ok {
+		return fmt.Errorf("cannot update signing secret: subscription %v not found", subscriptionID)
+	}
+	sub.SigningSecret = signingSecret
+	s.subscriptions[subscriptionID] = sub
+	return nil
+}
+
+type subscriptionIterator struct {
+	subscriptions []store.Subscription
+}
+
+func (i *subscriptionIterator) Next(ctx context.Context, s *store.Subscription) bool {
+	if len(i.subscriptions) == 0 {
+		return false
+	}
+	*s = i.subscriptions[0]
+	i.subscriptions = i.subscriptions[1:]
+	return true
+}
+
+func (i *subscriptionIterator) Err() error {
+	return nil
+}
@@ -0,0 +1,256 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_FindOrCreateSubscription(t *testing.T) {
+	type args struct {
+		sub store.Subscription
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    store.Subscription
+		wantErr bool
+	}{
+		{
+			name: "Type_Devices - valid",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "0",
+					Type:            store.Type_Devices,
+					LinkedAccountID: "testLinkedAccountID",
+				},
+			},
+			want: store.Subscription{
+				SubscriptionID:  "0",
+				Type:            store.Type_Devices,
+				LinkedAccountID: "testLinkedAccountID",
+			},
+		},
+		{
+			name: "Type_Devices - valid - duplicit with same LinkedAccountID",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "0",
+					Type:            store.Type_Devices,
+					LinkedAccountID: "testLinkedAccountID",
+				},
+			},
+			want: store.Subscription{
+				SubscriptionID:  "0",
+				Type:            store.Type_Devices,
+				LinkedAccountID: "testLinkedAccountID",
+			},
+		},
+		{
+			name: "Type_Devices - error - duplicit with different SubscriptionID",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "1",
+					Type:            store.Type_Devices,
+					LinkedAccountID: "testLinkedAccountID",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Device - error - invalid DeviceID",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "1",
+					Type:            store.Type_Device,
+					LinkedAccountID: "testLinkedAccountID",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Device - valid",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "1",
+					Type:            store.Type_Device,
+					LinkedAccountID: "testLinkedAccountID",
+					DeviceID:        "testDeviceID",
+				},
+			},
+			want: store.Subscription{
+				SubscriptionID:  "1",
+				Type:            store.Type_Device,
+				LinkedAccountID: "testLinkedAccountID",
+				DeviceID:        "testDeviceID",
+			},
+		},
+		{
+			name: "Type_Device - error - duplicit with different SubscriptionID",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "2",
+					Type:            store.Type_Device,
+					LinkedAccountID: "testLinkedAccountID",
+					DeviceID:        "testDeviceID",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Resource - valid",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "2",
+					Type:            store.Type_Resource,
+					LinkedAccountID: "testLinkedAccountID",
+					DeviceID:        "testDeviceID",
+					Href:            "testHref",
+				},
+			},
+			want: store.Subscription{
+				SubscriptionID:  "2",
+				Type:            store.Type_Resource,
+				LinkedAccountID: "testLinkedAccountID",
+				DeviceID:        "testDeviceID",
+				Href:            "testHref",
+			},
+		},
+		{
+			name: "Type_Resource - error - duplicit SubscriptionID for different resource",
+			args: args{
+				sub: store.Subscription{
+					SubscriptionID:  "2",
+					Type:            store.Type_Resource,
+					LinkedAccountID: "testLinkedAccountID",
+					DeviceID:        "testDeviceID",
+					Href:            "testHref2",
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s := NewStore()
+	assert := assert.New(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.FindOrCreateSubscription(ctx, tt.args.sub)
+			if tt.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.Equal(tt.want, got)
+			}
+		})
+	}
+}
+
+type testSubscriptionHandler struct {
+	subs []store.Subscription
+}
+
+func (h *testSubscriptionHandler) Handle(ctx context.Context, iter store.SubscriptionIter) (err error) {
+	var sub store.Subscription
+	for iter.Next(ctx, &sub) {
+		h.subs = append(h.subs, sub)
+	}
+	return iter.Err()
+}
+
+func TestStore_LoadSubscriptions(t *testing.T) {
+	subs := []store.Subscription{
+		store.Subscription{
+			SubscriptionID:  "0",
+			Type:            store.Type_Devices,
+			LinkedAccountID: "testLinkedAccountID",
+		},
+		store.Subscription{
+			SubscriptionID:  "1",
+			Type:            store.Type_Device,
+			LinkedAccountID: "testLinkedAccountID",
+			DeviceID:        "testDeviceID",
+		},
+		store.Subscription{
+			SubscriptionID:  "2",
+			Type:            store.Type_Resource,
+			LinkedAccountID: "testLinkedAccountID",
+			DeviceID:        "testDeviceID",
+			Href:            "testHref",
+		},
+	}
+
+	type args struct {
+		queries []store.SubscriptionQuery
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+		want    []store.Subscription
+	}{
+		{
+			name: "all",
+			want: subs,
+		},
+		{
+			name: "bySubscriptionID",
+			args: args{
+				queries: []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: "2"}},
+			},
+			want: []store.Subscription{subs[2]},
+		},
+		{
+			name: "byResource",
+			args: args{
+				queries: []store.SubscriptionQuery{store.SubscriptionQuery{Type: store.Type_Resource, DeviceID: "testDeviceID", Href: "testHref"}},
+			},
+			want: []store.Subscription{subs[2]},
+		},
+		{
+			name: "bySubscriptionIDOrDevice",
+			args: args{
+				queries: []store.SubscriptionQuery{
+					store.SubscriptionQuery{SubscriptionID: "0"},
+					store.SubscriptionQuery{Type: store.Type_Device, DeviceID: "testDeviceID"},
+				},
+			},
+			want: []store.Subscription{subs[0], subs[1]},
+		},
+		{
+			name: "invalid - DeviceID without Type",
+			args: args{
+				queries: []store.SubscriptionQuery{store.SubscriptionQuery{DeviceID: "testDeviceID"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	require := require.New(t)
+	ctx := context.Background()
+	s := NewStore()
+	assert := assert.New(t)
+
+	for _, sub := range subs {
+		_, err := s.FindOrCreateSubscription(ctx, sub)
+		require.NoError(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h testSubscriptionHandler
+			err := s.LoadSubscriptions(ctx, tt.args.queries, &h)
+			if tt.wantErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+				assert.Equal(tt.want, h.subs)
+			}
+		})
+	}
+}
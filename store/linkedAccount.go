@@ -2,12 +2,14 @@ package store
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
-	jwt "github.com/dgrijalva/jwt-go"
 	"golang.org/x/oauth2"
+	"golang.org/x/sync/singleflight"
 )
 
 type AccessToken string
@@ -31,37 +33,84 @@ func (h *LinkedCloudsHandler) Handle(ctx context.Context, iter LinkedCloudIter)
 	return iter.Err()
 }
 
-func (o OAuth) Refresh(ctx context.Context, s Store) (OAuth, error) {
+// errMissingRefreshToken the access token was issued without the refresh token, so it cannot be renewed.
+var errMissingRefreshToken = fmt.Errorf("refresh token is not set")
+
+// Refresh obtains new tokens from the token endpoint of the linked cloud which issued them. Tokens of a client
+// credentials linked cloud are obtained again by the client credentials grant.
+func (o OAuth) Refresh(ctx context.Context, l LinkedCloud) (OAuth, error) {
 	if o.Expiry.IsZero() {
 		return o, nil
 	}
-	var h LinkedCloudsHandler
-	err := s.LoadLinkedClouds(ctx, Query{ID: o.LinkedCloudID}, &h)
-	if err != nil {
-		return o, err
+	if l.IsClientCredentials() {
+		t, err := l.ClientCredentialsToken(ctx)
+		if err != nil {
+			return o, err
+		}
+		t.LinkedCloudID = o.LinkedCloudID
+		return t, nil
 	}
-	if len(h.LinkedClouds) != 1 {
-		return o, fmt.Errorf("linked cloud %v not found", o.LinkedCloudID)
+	if o.RefreshToken == "" {
+		return o, errMissingRefreshToken
 	}
-	l := h.LinkedClouds[0]
-	c := l.ToOAuth2Config()
-	restoredToken := oauth2.Token{
-		RefreshToken: o.RefreshToken,
+	client, err := l.HTTPClient()
+	if err != nil {
+		return o, err
 	}
-	ctx = context.WithValue(ctx, oauth2.HTTPClient, http.DefaultClient)
-	tokenSource := c.TokenSource(ctx, &restoredToken)
-	token, err := tokenSource.Token()
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, client)
+	token, err := l.refreshTokenSource(ctx, o.RefreshToken).Token()
 	if err != nil {
 		return o, err
 	}
+	refreshToken := token.RefreshToken
+	if refreshToken == "" {
+		// the authorization server doesn't rotate the refresh token (RFC 6749 section 6),
+		// don't rely on the oauth2 package to copy it from the request
+		refreshToken = o.RefreshToken
+	}
 	return OAuth{
 		LinkedCloudID: o.LinkedCloudID,
 		AccessToken:   AccessToken(token.AccessToken),
 		Expiry:        token.Expiry,
-		RefreshToken:  token.RefreshToken,
+		RefreshToken:  refreshToken,
 	}, nil
 }
 
+// IsPermanentRefreshError reports whether the token endpoint rejected the refresh token or the client, or the refresh token
+// is missing, so the refresh cannot succeed without a new authorization of the user.
+func IsPermanentRefreshError(err error) bool {
+	if err == errMissingRefreshToken {
+		return true
+	}
+	rerr, ok := err.(*oauth2.RetrieveError)
+	if !ok || rerr.Response == nil {
+		return false
+	}
+	if rerr.Response.StatusCode != http.StatusBadRequest && rerr.Response.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(rerr.Body, &body) != nil {
+		vals, perr := url.ParseQuery(string(rerr.Body))
+		if perr != nil {
+			return false
+		}
+		body.Error = vals.Get("error")
+	}
+	switch body.Error {
+	case "invalid_grant", "invalid_client", "unauthorized_client":
+		return true
+	}
+	return false
+}
+
+// IsExpiring reports whether the access token expires before the deadline and so it should be refreshed.
+func (o OAuth) IsExpiring(deadline time.Time) bool {
+	return !o.Expiry.IsZero() && !o.Expiry.After(deadline)
+}
+
 func (o OAuth) IsValidAccessToken() bool {
 	if o.Expiry.IsZero() || o.Expiry.UnixNano() > time.Now().UnixNano() {
 		return true
@@ -76,68 +125,186 @@ func (o OAuth) GetAccessToken() (AccessToken, error) {
 	return AccessToken(""), fmt.Errorf("cannot get accesstoken: token is invalid")
 }
 
-type claims struct {
-	Subject string `json:"sub,omitempty"`
+type LinkedAccount struct {
+	ID string
+	// UserID is the owner of the linked account in the origin cloud.
+	UserID      string
+	TargetURL   string
+	TargetCloud OAuth
+	OriginCloud OAuth
+	Status      LinkedAccountStatus
+	// LastError is the last failure of the linked account, it is kept after the linked account recovers.
+	LastError   string
+	LastErrorAt time.Time
+	// StatusChangedAt is the time of the last change of the Status.
+	StatusChangedAt time.Time
+	// TargetReauthRequired the refresh token of the target cloud was rejected, so the linked account is activated
+	// only by the reauthorization of the target cloud.
+	TargetReauthRequired bool
+	// Version is incremented by each update of the linked account in the store.
+	Version uint64
 }
 
-func (c *claims) Valid() error {
-	return nil
+// RefreshTokens refreshes expired access tokens of the target and the origin cloud. The target linked cloud is loaded
+// from the store, the origin cloud is configured by the service.
+func (l LinkedAccount) RefreshTokens(ctx context.Context, s Store, originCloud LinkedCloud) (LinkedAccount, error) {
+	return l.RefreshTokensExpiringBefore(ctx, s, originCloud, time.Now())
 }
 
-func parseSubFromJwtToken(rawJwtToken string) (string, error) {
-	parser := &jwt.Parser{
-		SkipClaimsValidation: true,
-	}
+// refreshGroup coalesces concurrent refreshes of the same linked account, so the refresh token is used only once.
+var refreshGroup singleflight.Group
 
-	var claims claims
-	_, _, err := parser.ParseUnverified(rawJwtToken, &claims)
-	if err != nil {
-		return "", fmt.Errorf("cannot get subject from jwt token: %v", err)
-	}
+// maxUpdateAttempts limits reloads of a linked account which was updated concurrently by another replica.
+const maxUpdateAttempts = 3
 
-	if claims.Subject != "" {
-		return claims.Subject, nil
+// RefreshTokensExpiringBefore refreshes access tokens which expire before the deadline and stores them.
+// Concurrent refreshes of the linked account share one request to the token endpoint, the refresh is repeated once when
+// the shared tokens expire before the deadline of the caller. The linked account is reloaded
+// from the store before the refresh and stored only when it wasn't modified meanwhile, so rotated refresh tokens are not lost.
+// When a refresh token is rejected permanently, the linked account is stored with LinkedAccountStatus_REAUTH_REQUIRED.
+func (l LinkedAccount) RefreshTokensExpiringBefore(ctx context.Context, s Store, originCloud LinkedCloud, deadline time.Time) (LinkedAccount, error) {
+	if !l.TargetCloud.IsExpiring(deadline) && !l.OriginCloud.IsExpiring(deadline) {
+		return l, nil
+	}
+	for i := 0; ; i++ {
+		var started bool
+		v, err, _ := refreshGroup.Do(l.ID, func() (interface{}, error) {
+			started = true
+			return refreshLinkedAccount(ctx, s, originCloud, l.ID, deadline)
+		})
+		if err != nil {
+			return l, err
+		}
+		r := v.(LinkedAccount)
+		// a refresh started by a concurrent caller checked its own deadline, so its tokens may expire before this deadline
+		if started || i > 0 || (!r.TargetCloud.IsExpiring(deadline) && !r.OriginCloud.IsExpiring(deadline)) {
+			return r, nil
+		}
 	}
-
-	return "", fmt.Errorf("cannot get subject from jwt token: not found")
 }
 
-func (t AccessToken) GetSubject() (string, error) {
-	return parseSubFromJwtToken(string(t))
+func loadLinkedAccount(ctx context.Context, s Store, linkedAccountID string) (LinkedAccount, error) {
+	var h linkedAccountsHandler
+	err := s.LoadLinkedAccounts(ctx, Query{ID: linkedAccountID}, &h)
+	if err != nil {
+		return LinkedAccount{}, fmt.Errorf("cannot load linked account %v: %v", linkedAccountID, err)
+	}
+	if len(h.linkedAccounts) != 1 {
+		return LinkedAccount{}, fmt.Errorf("cannot load linked account %v: not found", linkedAccountID)
+	}
+	return h.linkedAccounts[0], nil
 }
 
-type LinkedAccount struct {
-	ID          string
-	TargetURL   string
-	TargetCloud OAuth
-	OriginCloud OAuth
+func refreshLinkedAccount(ctx context.Context, s Store, originCloud LinkedCloud, linkedAccountID string, deadline time.Time) (LinkedAccount, error) {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		l, err := loadLinkedAccount(ctx, s, linkedAccountID)
+		if err != nil {
+			return l, fmt.Errorf("cannot refresh tokens: %v", err)
+		}
+		l, err = l.refreshTokens(ctx, s, originCloud, deadline)
+		if !IsConflictError(err) {
+			return l, err
+		}
+	}
+	return LinkedAccount{}, fmt.Errorf("cannot refresh tokens: %v", ConflictError{Kind: "linked account", ID: linkedAccountID})
 }
 
-func (l LinkedAccount) RefreshTokens(ctx context.Context, s Store) (LinkedAccount, error) {
-	if l.TargetCloud.IsValidAccessToken() && l.OriginCloud.IsValidAccessToken() {
+func (l LinkedAccount) refreshTokens(ctx context.Context, s Store, originCloud LinkedCloud, deadline time.Time) (LinkedAccount, error) {
+	if !l.TargetCloud.IsExpiring(deadline) && !l.OriginCloud.IsExpiring(deadline) {
 		return l, nil
 	}
-	t := l.TargetCloud
-	o := l.OriginCloud
+	if l.IsReauthRequired() {
+		return l, fmt.Errorf("cannot refresh tokens: linked account %v requires reauthorization", l.ID)
+	}
+	if l.IsDisabled() {
+		return l, fmt.Errorf("cannot refresh tokens: linked account %v is disabled", l.ID)
+	}
 	var err error
-	if !t.IsValidAccessToken() {
-		t, err = t.Refresh(ctx, s)
+	var targetRefreshed bool
+	if l.TargetCloud.IsExpiring(deadline) {
+		var h LinkedCloudsHandler
+		err = s.LoadLinkedClouds(ctx, Query{ID: l.TargetCloud.LinkedCloudID}, &h)
 		if err != nil {
-			return l, fmt.Errorf("cannot refreash target cloud access token: %v", err)
+			return l, fmt.Errorf("cannot refresh target cloud access token: %v", err)
+		}
+		if len(h.LinkedClouds) != 1 {
+			return l, fmt.Errorf("cannot refresh target cloud access token: linked cloud %v not found", l.TargetCloud.LinkedCloudID)
 		}
+		t, err := l.TargetCloud.Refresh(ctx, h.LinkedClouds[0])
+		if err != nil {
+			if IsPermanentRefreshError(err) {
+				l.TargetReauthRequired = true
+			}
+			return l.refreshFailed(ctx, s, fmt.Errorf("cannot refresh target cloud access token: %v", err), IsPermanentRefreshError(err), false)
+		}
+		l.TargetCloud = t
+		targetRefreshed = true
 	}
-	if !o.IsValidAccessToken() {
-		o, err = o.Refresh(ctx, s)
+	if l.OriginCloud.IsExpiring(deadline) {
+		o, err := l.OriginCloud.Refresh(ctx, originCloud)
 		if err != nil {
-			return l, fmt.Errorf("cannot refresh target cloud access token: %v", err)
+			return l.refreshFailed(ctx, s, fmt.Errorf("cannot refresh origin cloud access token: %v", err), IsPermanentRefreshError(err), targetRefreshed)
 		}
+		l.OriginCloud = o
 	}
-	l.TargetCloud = t
-	l.OriginCloud = o
+	l.setRecovered(time.Now())
 
 	err = s.UpdateLinkedAccount(ctx, l)
+	if IsConflictError(err) {
+		return l, err
+	}
 	if err != nil {
 		return l, fmt.Errorf("cannot store updated linked account: %v", err)
 	}
+	l.Version++
 	return l, nil
 }
+
+// refreshFailed stores tokens which were already refreshed, because the refresh token may be rotated,
+// and records the failure to the linked account. ConflictError is returned when the linked account
+// was updated meanwhile, because the refresh token may be rejected just because another replica rotated it.
+func (l LinkedAccount) refreshFailed(ctx context.Context, s Store, err error, permanent, refreshed bool) (LinkedAccount, error) {
+	status := LinkedAccountStatus_DEGRADED
+	if permanent {
+		status = LinkedAccountStatus_REAUTH_REQUIRED
+	}
+	l.setFailure(status, err, time.Now())
+	uerr := s.UpdateLinkedAccount(ctx, l)
+	if IsConflictError(uerr) {
+		return l, uerr
+	}
+	if uerr != nil {
+		return l, fmt.Errorf("%v; cannot store linked account: %v", err, uerr)
+	}
+	l.Version++
+	return l, err
+}
+
+type linkedAccountsHandler struct {
+	linkedAccounts []LinkedAccount
+}
+
+func (h *linkedAccountsHandler) Handle(ctx context.Context, iter LinkedAccountIter) error {
+	var l LinkedAccount
+	for iter.Next(ctx, &l) {
+		h.linkedAccounts = append(h.linkedAccounts, l)
+	}
+	return iter.Err()
+}
+
+// Validate checks that the linked account can be stored.
+func (l LinkedAccount) Validate() error {
+	if l.ID == "" {
+		return fmt.Errorf("cannot save linked account: invalid ID")
+	}
+	if l.TargetCloud.LinkedCloudID == "" {
+		return fmt.Errorf("cannot save linked account: invalid ConfigId")
+	}
+	if l.TargetCloud.AccessToken == "" && l.TargetCloud.RefreshToken == "" {
+		return fmt.Errorf("cannot save linked account: invalid AccessToken and RefreshToken")
+	}
+	if l.TargetURL == "" {
+		return fmt.Errorf("cannot save linked account: invalid TargetURL")
+	}
+	return nil
+}
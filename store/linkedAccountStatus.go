@@ -0,0 +1,153 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type LinkedAccountStatus string
+
+const (
+	// LinkedAccountStatus_ACTIVE the linked account works. Linked accounts without status are active.
+	LinkedAccountStatus_ACTIVE LinkedAccountStatus = "active"
+	// LinkedAccountStatus_DEGRADED the last use of the linked account failed, it recovers by the next successful use
+	LinkedAccountStatus_DEGRADED LinkedAccountStatus = "degraded"
+	// LinkedAccountStatus_REAUTH_REQUIRED a refresh token was rejected, the user must authorize the linked account again
+	LinkedAccountStatus_REAUTH_REQUIRED LinkedAccountStatus = "reauth_required"
+	// LinkedAccountStatus_DISABLED the linked account was disabled by an administrator, it is not used
+	LinkedAccountStatus_DISABLED LinkedAccountStatus = "disabled"
+)
+
+// severity orders statuses, so a failure doesn't hide a more severe status.
+func (s LinkedAccountStatus) severity() int {
+	switch s {
+	case LinkedAccountStatus_DEGRADED:
+		return 1
+	case LinkedAccountStatus_REAUTH_REQUIRED:
+		return 2
+	case LinkedAccountStatus_DISABLED:
+		return 3
+	}
+	return 0
+}
+
+// IsReauthRequired reports whether tokens of the linked account cannot be refreshed anymore.
+func (l LinkedAccount) IsReauthRequired() bool {
+	return l.Status == LinkedAccountStatus_REAUTH_REQUIRED
+}
+
+// IsDisabled reports whether the linked account must not be used.
+func (l LinkedAccount) IsDisabled() bool {
+	return l.Status == LinkedAccountStatus_DISABLED
+}
+
+// setStatus reports whether the status was changed.
+func (l *LinkedAccount) setStatus(status LinkedAccountStatus, now time.Time) bool {
+	if l.Status == status || (l.Status == "" && status == LinkedAccountStatus_ACTIVE) {
+		return false
+	}
+	l.Status = status
+	l.StatusChangedAt = now
+	return true
+}
+
+// setFailure records the failure. The status is changed only to a more severe one.
+func (l *LinkedAccount) setFailure(status LinkedAccountStatus, failure error, now time.Time) {
+	if status.severity() > l.Status.severity() {
+		l.setStatus(status, now)
+	}
+	l.LastError = failure.Error()
+	l.LastErrorAt = now
+}
+
+// setRecovered activates the degraded linked account. It reports whether the linked account was changed.
+func (l *LinkedAccount) setRecovered(now time.Time) bool {
+	if l.Status != LinkedAccountStatus_DEGRADED {
+		return false
+	}
+	return l.setStatus(LinkedAccountStatus_ACTIVE, now)
+}
+
+// updateLinkedAccount stores the linked account modified by the update. The linked account is reloaded
+// when it was updated concurrently. The update reports whether the linked account was changed.
+func updateLinkedAccount(ctx context.Context, s Store, linkedAccountID string, update func(l *LinkedAccount) bool) (LinkedAccount, error) {
+	for i := 0; i < maxUpdateAttempts; i++ {
+		l, err := loadLinkedAccount(ctx, s, linkedAccountID)
+		if err != nil {
+			return l, err
+		}
+		if !update(&l) {
+			return l, nil
+		}
+		err = s.UpdateLinkedAccount(ctx, l)
+		if IsConflictError(err) {
+			continue
+		}
+		if err != nil {
+			return l, fmt.Errorf("cannot store linked account %v: %v", linkedAccountID, err)
+		}
+		l.Version++
+		return l, nil
+	}
+	return LinkedAccount{}, ConflictError{Kind: "linked account", ID: linkedAccountID}
+}
+
+// RecordLinkedAccountFailure stores the failure of the linked account and marks the active linked account as degraded.
+func RecordLinkedAccountFailure(ctx context.Context, s Store, linkedAccountID string, failure error) error {
+	_, err := updateLinkedAccount(ctx, s, linkedAccountID, func(l *LinkedAccount) bool {
+		l.setFailure(LinkedAccountStatus_DEGRADED, failure, time.Now())
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("cannot record failure of linked account: %v", err)
+	}
+	return nil
+}
+
+// RecordLinkedAccountSuccess marks the degraded linked account as active. The store is not accessed
+// when the loaded linked account isn't degraded.
+func RecordLinkedAccountSuccess(ctx context.Context, s Store, l LinkedAccount) error {
+	if l.Status != LinkedAccountStatus_DEGRADED {
+		return nil
+	}
+	_, err := updateLinkedAccount(ctx, s, l.ID, func(l *LinkedAccount) bool {
+		return l.setRecovered(time.Now())
+	})
+	if err != nil {
+		return fmt.Errorf("cannot record success of linked account: %v", err)
+	}
+	return nil
+}
+
+// SetLinkedAccountStatus sets the status of the linked account regardless of its current status.
+func SetLinkedAccountStatus(ctx context.Context, s Store, linkedAccountID string, status LinkedAccountStatus) (LinkedAccount, error) {
+	l, err := updateLinkedAccount(ctx, s, linkedAccountID, func(l *LinkedAccount) bool {
+		return l.setStatus(status, time.Now())
+	})
+	if err != nil {
+		return l, fmt.Errorf("cannot set status of linked account: %v", err)
+	}
+	return l, nil
+}
+
+// ReauthorizeLinkedAccount replaces tokens of the linked account by tokens obtained by a new authorization of the user.
+// The target cloud tokens are kept when targetCloud is nil. The linked account is activated unless it is disabled
+// or the target cloud requires reauthorization and targetCloud is nil.
+func ReauthorizeLinkedAccount(ctx context.Context, s Store, linkedAccountID string, originCloud OAuth, targetCloud *OAuth) (LinkedAccount, error) {
+	l, err := updateLinkedAccount(ctx, s, linkedAccountID, func(l *LinkedAccount) bool {
+		l.OriginCloud = originCloud
+		if targetCloud != nil {
+			l.TargetCloud = *targetCloud
+			l.TargetReauthRequired = false
+		}
+		if !l.IsDisabled() && !l.TargetReauthRequired {
+			l.setStatus(LinkedAccountStatus_ACTIVE, time.Now())
+		}
+		return true
+	})
+	if err != nil {
+		return l, fmt.Errorf("cannot reauthorize linked account: %v", err)
+	}
+	return l, nil
+}
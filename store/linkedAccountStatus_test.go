@@ -0,0 +1,94 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkedAccountStatus(t *testing.T) {
+	ctx := context.Background()
+	s, l := newTestStore(t, "testTokenURL")
+	load := func() store.LinkedAccount {
+		var h testLinkedAccountHandler
+		require.NoError(t, s.LoadLinkedAccounts(ctx, store.Query{ID: l.ID}, &h))
+		require.Len(t, h.linkedAccounts, 1)
+		return h.linkedAccounts[0]
+	}
+
+	require.NoError(t, store.RecordLinkedAccountSuccess(ctx, s, load()))
+	got := load()
+	assert.Equal(t, uint64(0), got.Version)
+	assert.Empty(t, got.Status)
+
+	require.NoError(t, store.RecordLinkedAccountFailure(ctx, s, l.ID, errors.New("testError")))
+	got = load()
+	assert.Equal(t, store.LinkedAccountStatus_DEGRADED, got.Status)
+	assert.Equal(t, "testError", got.LastError)
+	assert.False(t, got.LastErrorAt.IsZero())
+	assert.False(t, got.StatusChangedAt.IsZero())
+
+	require.NoError(t, store.RecordLinkedAccountSuccess(ctx, s, got))
+	got = load()
+	assert.Equal(t, store.LinkedAccountStatus_ACTIVE, got.Status)
+	assert.Equal(t, "testError", got.LastError)
+
+	_, err := store.SetLinkedAccountStatus(ctx, s, l.ID, store.LinkedAccountStatus_DISABLED)
+	require.NoError(t, err)
+	require.NoError(t, store.RecordLinkedAccountFailure(ctx, s, l.ID, errors.New("testError2")))
+	got = load()
+	assert.Equal(t, store.LinkedAccountStatus_DISABLED, got.Status)
+	assert.Equal(t, "testError2", got.LastError)
+
+	_, err = got.RefreshTokens(ctx, s, store.LinkedCloud{})
+	assert.Error(t, err)
+
+	_, err = store.SetLinkedAccountStatus(ctx, s, "notFound", store.LinkedAccountStatus_ACTIVE)
+	assert.Error(t, err)
+}
+
+func TestReauthorizeLinkedAccountWithRevokedTarget(t *testing.T) {
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer tokenServer.Close()
+	ctx := context.Background()
+	s, l := newTestStore(t, tokenServer.URL)
+
+	_, err := l.RefreshTokens(ctx, s, store.LinkedCloud{})
+	require.Error(t, err)
+
+	origin := store.OAuth{AccessToken: "newOriginAccessToken"}
+	got, err := store.ReauthorizeLinkedAccount(ctx, s, l.ID, origin, nil)
+	require.NoError(t, err)
+	assert.Equal(t, store.LinkedAccountStatus_REAUTH_REQUIRED, got.Status)
+	assert.True(t, got.TargetReauthRequired)
+	assert.Equal(t, origin, got.OriginCloud)
+
+	target := store.OAuth{LinkedCloudID: "testLinkedCloudID", AccessToken: "newTargetAccessToken", RefreshToken: "newTargetRefreshToken"}
+	got, err = store.ReauthorizeLinkedAccount(ctx, s, l.ID, origin, &target)
+	require.NoError(t, err)
+	assert.Equal(t, store.LinkedAccountStatus_ACTIVE, got.Status)
+	assert.False(t, got.TargetReauthRequired)
+	assert.Equal(t, target, got.TargetCloud)
+}
+
+type testLinkedAccountHandler struct {
+	linkedAccounts []store.LinkedAccount
+}
+
+func (h *testLinkedAccountHandler) Handle(ctx context.Context, iter store.LinkedAccountIter) error {
+	var l store.LinkedAccount
+	for iter.Next(ctx, &l) {
+		h.linkedAccounts = append(h.linkedAccounts, l)
+	}
+	return iter.Err()
+}
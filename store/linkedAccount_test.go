@@ -0,0 +1,171 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T, tokenURL string) (*inmemory.Store, store.LinkedAccount) {
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testLinkedCloudID",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: tokenURL, TokenUrl: tokenURL},
+	}))
+	l := store.LinkedAccount{
+		ID:        "testID",
+		TargetURL: "testTargetURL",
+		TargetCloud: store.OAuth{
+			LinkedCloudID: "testLinkedCloudID",
+			AccessToken:   "oldAccessToken",
+			RefreshToken:  "oldRefreshToken",
+			Expiry:        time.Now().Add(-time.Minute),
+		},
+	}
+	require.NoError(t, s.InsertLinkedAccount(ctx, l))
+	return s, l
+}
+
+func TestRefreshTokensCoalesced(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"newAccessToken","refresh_token":"newRefreshToken","token_type":"bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+	s, l := newTestStore(t, tokenServer.URL)
+
+	const n = 8
+	var wg sync.WaitGroup
+	results := make([]store.LinkedAccount, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = l.RefreshTokens(context.Background(), s, store.LinkedCloud{})
+		}(i)
+	}
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for i := 0; i < n; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, "newRefreshToken", results[i].TargetCloud.RefreshToken)
+	}
+}
+
+func TestRefreshTokensConcurrentUpdate(t *testing.T) {
+	var s *inmemory.Store
+	var rotate sync.Once
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// another replica rotates the refresh token first
+		rotate.Do(func() {
+			l := store.LinkedAccount{
+				ID:        "testID",
+				TargetURL: "testTargetURL",
+				TargetCloud: store.OAuth{
+					LinkedCloudID: "testLinkedCloudID",
+					AccessToken:   "otherAccessToken",
+					RefreshToken:  "otherRefreshToken",
+					Expiry:        time.Now().Add(time.Hour),
+				},
+			}
+			require.NoError(t, s.UpdateLinkedAccount(context.Background(), l))
+		})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer tokenServer.Close()
+	s, l := newTestStore(t, tokenServer.URL)
+
+	got, err := l.RefreshTokens(context.Background(), s, store.LinkedCloud{})
+	require.NoError(t, err)
+	assert.Equal(t, "otherRefreshToken", got.TargetCloud.RefreshToken)
+	assert.False(t, got.IsReauthRequired())
+	assert.Equal(t, uint64(1), got.Version)
+}
+
+func TestRefreshTokensCoalescedWithLaterDeadline(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expiresIn := 3600
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			expiresIn = 60
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"newAccessToken","refresh_token":"newRefreshToken","token_type":"bearer","expires_in":%v}`, expiresIn)
+	}))
+	defer tokenServer.Close()
+	s, l := newTestStore(t, tokenServer.URL)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := l.RefreshTokens(context.Background(), s, store.LinkedCloud{})
+		assert.NoError(t, err)
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	deadline := time.Now().Add(10 * time.Minute)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	got, err := l.RefreshTokensExpiringBefore(context.Background(), s, store.LinkedCloud{}, deadline)
+	wg.Wait()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.False(t, got.TargetCloud.IsExpiring(deadline))
+}
+
+func TestRefreshTokensWithoutRotation(t *testing.T) {
+	var refreshTokens []string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		refreshTokens = append(refreshTokens, r.PostForm.Get("refresh_token"))
+		// the refresh token is not rotated, so the response doesn't contain it
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"newAccessToken","token_type":"bearer","expires_in":1}`))
+	}))
+	defer tokenServer.Close()
+	s, l := newTestStore(t, tokenServer.URL)
+
+	got, err := l.RefreshTokens(context.Background(), s, store.LinkedCloud{})
+	require.NoError(t, err)
+	assert.Equal(t, "oldRefreshToken", got.TargetCloud.RefreshToken)
+
+	// the next refresh uses the same refresh token
+	got, err = got.RefreshTokensExpiringBefore(context.Background(), s, store.LinkedCloud{}, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "oldRefreshToken", got.TargetCloud.RefreshToken)
+	assert.False(t, got.IsReauthRequired())
+	assert.Equal(t, []string{"oldRefreshToken", "oldRefreshToken"}, refreshTokens)
+}
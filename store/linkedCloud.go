@@ -1,12 +1,53 @@
 package store
 
-import "golang.org/x/oauth2"
+import (
+	"fmt"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthStyle of the client authentication at the token endpoint.
+type AuthStyle string
+
+const (
+	// AuthStyle_HEADER the client ID and secret are sent by HTTP Basic authentication.
+	AuthStyle_HEADER AuthStyle = "header"
+	// AuthStyle_PARAMS the client ID and secret are sent in the body of the request.
+	AuthStyle_PARAMS AuthStyle = "params"
+)
+
+func (s AuthStyle) toOAuth2() oauth2.AuthStyle {
+	switch s {
+	case AuthStyle_HEADER:
+		return oauth2.AuthStyleInHeader
+	case AuthStyle_PARAMS:
+		return oauth2.AuthStyleInParams
+	}
+	return oauth2.AuthStyleAutoDetect
+}
 
 type Endpoint struct {
-	AuthUrl  string `json:"AuthUrl" envconfig:"AUTH_URL" required:"true"`
-	TokenUrl string `json:"TokenUrl" envconfig:"TOKEN_URL" required:"true"`
+	AuthUrl  string `json:"AuthUrl" envconfig:"AUTH_URL"`
+	TokenUrl string `json:"TokenUrl" envconfig:"TOKEN_URL"`
+	// RevocationUrl of the token revocation (RFC 7009). Tokens are not revoked when it is empty.
+	RevocationUrl string `json:"RevocationUrl" envconfig:"REVOCATION_URL"`
+	// DeviceAuthorizationUrl of the device authorization grant (RFC 8628). Linked accounts cannot be added by the device flow when it is empty.
+	DeviceAuthorizationUrl string `json:"DeviceAuthorizationUrl" envconfig:"DEVICE_AUTHORIZATION_URL"`
+	// AuthStyle of the token endpoint. Both styles are tried when it is empty.
+	AuthStyle AuthStyle `json:"AuthStyle" envconfig:"AUTH_STYLE"`
 }
 
+// GrantType of the OAuth flow which obtains tokens of the linked cloud.
+type GrantType string
+
+const (
+	// GrantType_AUTHORIZATION_CODE the user authorizes the linked account by the browser. Linked clouds without grant type use it.
+	GrantType_AUTHORIZATION_CODE GrantType = "authorization_code"
+	// GrantType_CLIENT_CREDENTIALS tokens are issued to the client of the connector without the user (RFC 6749 section 4.4).
+	GrantType_CLIENT_CREDENTIALS GrantType = "client_credentials"
+)
+
 type LinkedCloud struct {
 	ID           string   `json:"ID"`
 	Name         string   `json:"Name" envconfig:"NAME" required:"true"`
@@ -15,6 +56,22 @@ type LinkedCloud struct {
 	Scopes       []string `json:"Scopes" envconfig:"SCOPES" required:"true"`
 	Endpoint     Endpoint `json:"Endpoint"`
 	Audience     string   `json:"Audience" envconfig:"AUDIENCE"`
+	// AuthParams are added to the authorization request, e.g. prompt, resource or acr_values.
+	AuthParams map[string]string `json:"AuthParams" envconfig:"AUTH_PARAMS"`
+	// TokenParams are added to requests to the token endpoint.
+	TokenParams map[string]string `json:"TokenParams" envconfig:"TOKEN_PARAMS"`
+	// JwksURL is used to verify access tokens issued by the cloud. The origin cloud must provide it.
+	JwksURL string `json:"JwksUrl" envconfig:"JWKS_URL"`
+	// Issuer of the OpenID provider. Endpoint and JwksURL are discovered from its openid-configuration when it is set.
+	Issuer string `json:"Issuer" envconfig:"ISSUER"`
+	// PKCE enables Proof Key for Code Exchange (RFC 7636) with the S256 method during account linking.
+	PKCE bool `json:"PKCE" envconfig:"PKCE"`
+	// GrantType of tokens of linked accounts. Linked accounts of a client_credentials linked cloud are added without the browser.
+	GrantType GrantType `json:"GrantType" envconfig:"GRANT_TYPE"`
+	// HTTP configures the client of requests to the linked cloud.
+	HTTP HTTPConfig `json:"HTTP" envconfig:"HTTP"`
+	// Version is incremented by each update of the linked cloud in the store. It is exposed by the REST API as ETag.
+	Version uint64 `json:"-" ignored:"true"`
 }
 
 func (l LinkedCloud) ToOAuth2Config() oauth2.Config {
@@ -23,8 +80,80 @@ func (l LinkedCloud) ToOAuth2Config() oauth2.Config {
 		ClientSecret: l.ClientSecret,
 		Scopes:       l.Scopes,
 		Endpoint: oauth2.Endpoint{
-			AuthURL:  l.Endpoint.AuthUrl,
-			TokenURL: l.Endpoint.TokenUrl,
+			AuthURL:   l.Endpoint.AuthUrl,
+			TokenURL:  l.Endpoint.TokenUrl,
+			AuthStyle: l.Endpoint.AuthStyle.toOAuth2(),
 		},
 	}
 }
+
+// AuthCodeOptions returns the Audience and AuthParams of the authorization request.
+func (l LinkedCloud) AuthCodeOptions() []oauth2.AuthCodeOption {
+	opts := make([]oauth2.AuthCodeOption, 0, len(l.AuthParams)+1)
+	if l.Audience != "" {
+		opts = append(opts, oauth2.SetAuthURLParam("audience", l.Audience))
+	}
+	for k, v := range l.AuthParams {
+		opts = append(opts, oauth2.SetAuthURLParam(k, v))
+	}
+	return opts
+}
+
+// TokenOptions returns the TokenParams of the authorization code exchange.
+func (l LinkedCloud) TokenOptions() []oauth2.AuthCodeOption {
+	opts := make([]oauth2.AuthCodeOption, 0, len(l.TokenParams))
+	for k, v := range l.TokenParams {
+		opts = append(opts, oauth2.SetAuthURLParam(k, v))
+	}
+	return opts
+}
+
+// tokenParams returns the TokenParams with the params of the request.
+func (l LinkedCloud) tokenParams(v url.Values) url.Values {
+	params := make(url.Values, len(l.TokenParams)+len(v))
+	for k, val := range l.TokenParams {
+		params.Set(k, val)
+	}
+	for k, val := range v {
+		params[k] = val
+	}
+	return params
+}
+
+// IsClientCredentials reports whether tokens of the linked cloud are obtained by the client credentials grant.
+func (l LinkedCloud) IsClientCredentials() bool {
+	return l.GrantType == GrantType_CLIENT_CREDENTIALS
+}
+
+// Validate checks that the linked cloud can be stored.
+func (l LinkedCloud) Validate() error {
+	if l.ID == "" {
+		return fmt.Errorf("cannot save linked cloud: invalid Id")
+	}
+	if l.ClientID == "" {
+		return fmt.Errorf("cannot save linked cloud: invalid ClientId")
+	}
+	if l.ClientSecret == "" {
+		return fmt.Errorf("cannot save linked cloud: invalid ClientSecret")
+	}
+	if len(l.Scopes) == 0 {
+		return fmt.Errorf("cannot save linked cloud: invalid Scopes")
+	}
+	switch l.Endpoint.AuthStyle {
+	case "", AuthStyle_HEADER, AuthStyle_PARAMS:
+	default:
+		return fmt.Errorf("cannot save linked cloud: invalid AuthStyle")
+	}
+	switch l.GrantType {
+	case "", GrantType_AUTHORIZATION_CODE, GrantType_CLIENT_CREDENTIALS:
+	default:
+		return fmt.Errorf("cannot save linked cloud: invalid GrantType")
+	}
+	if l.Endpoint.AuthUrl == "" && !l.IsClientCredentials() {
+		return fmt.Errorf("cannot save linked cloud: invalid AuthUrl")
+	}
+	if l.Endpoint.TokenUrl == "" {
+		return fmt.Errorf("cannot save linked cloud: invalid TokenUrl")
+	}
+	return nil
+}
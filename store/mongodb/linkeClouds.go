@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-ocf/openapi-connector/store"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,8 +13,42 @@ import (
 const resLinkedCloudCName = "LinkedCloud"
 
 type dbEndpoint struct {
-	AuthUrl  string
-	TokenUrl string
+	AuthUrl                string
+	TokenUrl               string
+	RevocationUrl          string
+	DeviceAuthorizationUrl string
+	AuthStyle              string
+}
+
+type dbHTTPConfig struct {
+	Timeout            int64
+	RootCAs            string
+	Certificate        string
+	PrivateKey         string
+	Proxy              string
+	InsecureSkipVerify bool
+}
+
+func makeDBHTTPConfig(c store.HTTPConfig) dbHTTPConfig {
+	return dbHTTPConfig{
+		Timeout:            int64(c.Timeout),
+		RootCAs:            c.RootCAs,
+		Certificate:        c.Certificate,
+		PrivateKey:         c.PrivateKey,
+		Proxy:              c.Proxy,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+}
+
+func (c dbHTTPConfig) toHTTPConfig() store.HTTPConfig {
+	return store.HTTPConfig{
+		Timeout:            time.Duration(c.Timeout),
+		RootCAs:            c.RootCAs,
+		Certificate:        c.Certificate,
+		PrivateKey:         c.PrivateKey,
+		Proxy:              c.Proxy,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
 }
 
 type dbLinkedCloud struct {
@@ -24,6 +59,14 @@ type dbLinkedCloud struct {
 	Scopes       []string
 	Endpoint     dbEndpoint
 	Audience     string
+	AuthParams   map[string]string
+	TokenParams  map[string]string
+	JwksUrl      string
+	Issuer       string
+	PKCE         bool
+	GrantType    string
+	HTTP         dbHTTPConfig
+	Version      int64 `bson:"version"`
 }
 
 func makeDBLinkedCloud(sub store.LinkedCloud) dbLinkedCloud {
@@ -34,57 +77,54 @@ func makeDBLinkedCloud(sub store.LinkedCloud) dbLinkedCloud {
 		ClientSecret: sub.ClientSecret,
 		Scopes:       sub.Scopes,
 		Audience:     sub.Audience,
+		AuthParams:   sub.AuthParams,
+		TokenParams:  sub.TokenParams,
+		JwksUrl:      sub.JwksURL,
+		Issuer:       sub.Issuer,
+		PKCE:         sub.PKCE,
+		GrantType:    string(sub.GrantType),
+		HTTP:         makeDBHTTPConfig(sub.HTTP),
+		Version:      int64(sub.Version),
 		Endpoint: dbEndpoint{
-			AuthUrl:  sub.Endpoint.AuthUrl,
-			TokenUrl: sub.Endpoint.TokenUrl,
+			AuthUrl:                sub.Endpoint.AuthUrl,
+			TokenUrl:               sub.Endpoint.TokenUrl,
+			RevocationUrl:          sub.Endpoint.RevocationUrl,
+			DeviceAuthorizationUrl: sub.Endpoint.DeviceAuthorizationUrl,
+			AuthStyle:              string(sub.Endpoint.AuthStyle),
 		},
 	}
 
 }
 
-func validateLinkedCloud(sub store.LinkedCloud) error {
-	if sub.ID == "" {
-		return fmt.Errorf("cannot save linked cloud: invalid Id")
-	}
-	if sub.ClientID == "" {
-		return fmt.Errorf("cannot save linked cloud: invalid ClientId")
-	}
-	if sub.ClientSecret == "" {
-		return fmt.Errorf("cannot save linked cloud: invalid ClientSecret")
-	}
-	if len(sub.Scopes) == 0 {
-		return fmt.Errorf("cannot save linked cloud: invalid Scopes")
-	}
-	if sub.Endpoint.AuthUrl == "" {
-		return fmt.Errorf("cannot save linked cloud: invalid AuthUrl")
-	}
-	if sub.Endpoint.TokenUrl == "" {
-		return fmt.Errorf("cannot save linked cloud: invalid TokenUrl")
-	}
-	return nil
-}
-
 func (s *Store) UpdateLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
-	err := validateLinkedCloud(sub)
+	err := sub.Validate()
 	if err != nil {
 		return err
 	}
 
 	dbSub := makeDBLinkedCloud(sub)
+	dbSub.Version++
 	col := s.client.Database(s.DBName()).Collection(resLinkedCloudCName)
 
-	res, err := col.UpdateOne(ctx, bson.M{"_id": sub.ID}, bson.M{"$set": dbSub})
+	res, err := col.UpdateOne(ctx, bson.M{"_id": sub.ID, versionKey: versionFilter(sub.Version)}, bson.M{"$set": dbSub})
 	if err != nil {
 		return fmt.Errorf("cannot save linked cloud: %v", err)
 	}
-	if res.MatchedCount == 0 {
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	count, err := col.CountDocuments(ctx, bson.M{"_id": sub.ID})
+	if err != nil {
+		return fmt.Errorf("cannot update linked cloud: %v", err)
+	}
+	if count == 0 {
 		return fmt.Errorf("cannot update linked cloud: not found")
 	}
-	return nil
+	return store.ConflictError{Kind: "linked cloud", ID: sub.ID}
 }
 
 func (s *Store) InsertLinkedCloud(ctx context.Context, sub store.LinkedCloud) error {
-	err := validateLinkedCloud(sub)
+	err := sub.Validate()
 	if err != nil {
 		return err
 	}
@@ -160,9 +200,20 @@ func (i *linkedCloudIterator) Next(ctx context.Context, s *store.LinkedCloud) bo
 	s.ClientSecret = sub.ClientSecret
 	s.Scopes = sub.Scopes
 	s.Audience = sub.Audience
+	s.AuthParams = sub.AuthParams
+	s.TokenParams = sub.TokenParams
+	s.JwksURL = sub.JwksUrl
+	s.Issuer = sub.Issuer
+	s.PKCE = sub.PKCE
+	s.GrantType = store.GrantType(sub.GrantType)
+	s.HTTP = sub.HTTP.toHTTPConfig()
+	s.Version = uint64(sub.Version)
 	s.Endpoint = store.Endpoint{
-		AuthUrl:  sub.Endpoint.AuthUrl,
-		TokenUrl: sub.Endpoint.TokenUrl,
+		AuthUrl:                sub.Endpoint.AuthUrl,
+		TokenUrl:               sub.Endpoint.TokenUrl,
+		RevocationUrl:          sub.Endpoint.RevocationUrl,
+		DeviceAuthorizationUrl: sub.Endpoint.DeviceAuthorizationUrl,
+		AuthStyle:              store.AuthStyle(sub.Endpoint.AuthStyle),
 	}
 
 	return true
@@ -11,6 +11,16 @@ import (
 )
 
 const resLinkedAccountCName = "linkedAccounts"
+const userIDKey = "userid"
+const targetLinkedCloudIDKey = "targetcloud.linkedcloudid"
+
+var linkedAccountUserIDQueryIndex = bson.D{
+	{Key: userIDKey, Value: 1},
+}
+
+var linkedAccountTargetLinkedCloudIDQueryIndex = bson.D{
+	{Key: targetLinkedCloudIDKey, Value: 1},
+}
 
 type dbOAuth struct {
 	LinkedCloudID string
@@ -20,10 +30,17 @@ type dbOAuth struct {
 }
 
 type dbLinkedAccount struct {
-	ID          string `bson:"_id"`
-	TargetURL   string
-	TargetCloud dbOAuth
-	OriginCloud dbOAuth
+	ID                   string `bson:"_id"`
+	UserID               string `bson:"userid"`
+	TargetURL            string
+	TargetCloud          dbOAuth
+	OriginCloud          dbOAuth
+	Status               string
+	LastError            string
+	LastErrorAt          int64
+	StatusChangedAt      int64
+	Version              int64 `bson:"version"`
+	TargetReauthRequired bool
 }
 
 func makeDBLinkedAccount(sub store.LinkedAccount) dbLinkedAccount {
@@ -32,12 +49,13 @@ func makeDBLinkedAccount(sub store.LinkedAccount) dbLinkedAccount {
 		targetExpiry = sub.TargetCloud.Expiry.UnixNano()
 	}
 	originExpiry := int64(0)
-	if !sub.TargetCloud.Expiry.IsZero() {
+	if !sub.OriginCloud.Expiry.IsZero() {
 		originExpiry = sub.OriginCloud.Expiry.UnixNano()
 	}
 
 	return dbLinkedAccount{
 		ID:        sub.ID,
+		UserID:    sub.UserID,
 		TargetURL: sub.TargetURL,
 		TargetCloud: dbOAuth{
 			LinkedCloudID: sub.TargetCloud.LinkedCloudID,
@@ -51,28 +69,18 @@ func makeDBLinkedAccount(sub store.LinkedAccount) dbLinkedAccount {
 			RefreshToken:  sub.OriginCloud.RefreshToken,
 			Expiry:        originExpiry,
 		},
+		Status:               string(sub.Status),
+		LastError:            sub.LastError,
+		LastErrorAt:          makeDBTime(sub.LastErrorAt),
+		StatusChangedAt:      makeDBTime(sub.StatusChangedAt),
+		Version:              int64(sub.Version),
+		TargetReauthRequired: sub.TargetReauthRequired,
 	}
 
 }
 
-func validateLinkedAccount(sub store.LinkedAccount) error {
-	if sub.ID == "" {
-		return fmt.Errorf("cannot save linked account: invalid ID")
-	}
-	if sub.TargetCloud.LinkedCloudID == "" {
-		return fmt.Errorf("cannot save linked account: invalid ConfigId")
-	}
-	if sub.TargetCloud.AccessToken == "" && sub.TargetCloud.RefreshToken == "" {
-		return fmt.Errorf("cannot save linked account: invalid AccessToken and RefreshToken")
-	}
-	if sub.TargetURL == "" {
-		return fmt.Errorf("cannot save linked account: invalid TargetURL")
-	}
-	return nil
-}
-
 func (s *Store) InsertLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
-	err := validateLinkedAccount(sub)
+	err := sub.Validate()
 	if err != nil {
 		return err
 	}
@@ -87,21 +95,29 @@ func (s *Store) InsertLinkedAccount(ctx context.Context, sub store.LinkedAccount
 }
 
 func (s *Store) UpdateLinkedAccount(ctx context.Context, sub store.LinkedAccount) error {
-	err := validateLinkedAccount(sub)
+	err := sub.Validate()
 	if err != nil {
 		return err
 	}
 	dbSub := makeDBLinkedAccount(sub)
+	dbSub.Version++
 	col := s.client.Database(s.DBName()).Collection(resLinkedAccountCName)
 
-	if res, err := col.UpdateOne(ctx, bson.M{"_id": sub.ID}, bson.M{"$set": dbSub}); err != nil {
+	res, err := col.UpdateOne(ctx, bson.M{"_id": sub.ID, versionKey: versionFilter(sub.Version)}, bson.M{"$set": dbSub})
+	if err != nil {
 		return fmt.Errorf("cannot update linked account: %v", err)
-	} else {
-		if res.MatchedCount == 0 {
-			return fmt.Errorf("cannot update linked account: not found")
-		}
 	}
-	return nil
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	count, err := col.CountDocuments(ctx, bson.M{"_id": sub.ID})
+	if err != nil {
+		return fmt.Errorf("cannot update linked account: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("cannot update linked account: not found")
+	}
+	return store.ConflictError{Kind: "linked account", ID: sub.ID}
 }
 
 func (s *Store) RemoveLinkedAccount(ctx context.Context, linkedAccountId string) error {
@@ -123,12 +139,17 @@ func (s *Store) LoadLinkedAccounts(ctx context.Context, query store.Query, h sto
 	var err error
 
 	col := s.client.Database(s.DBName()).Collection(resLinkedAccountCName)
-	switch {
-	case query.ID != "":
-		iter, err = col.Find(ctx, bson.M{"_id": query.ID})
-	default:
-		iter, err = col.Find(ctx, bson.M{})
+	q := bson.M{}
+	if query.ID != "" {
+		q["_id"] = query.ID
+	}
+	if query.UserID != "" {
+		q[userIDKey] = query.UserID
+	}
+	if query.LinkedCloudID != "" {
+		q[targetLinkedCloudIDKey] = query.LinkedCloudID
 	}
+	iter, err = col.Find(ctx, q)
 	if err == mongo.ErrNilDocument {
 		return nil
 	}
@@ -162,18 +183,25 @@ func (i *iterator) Next(ctx context.Context, s *store.LinkedAccount) bool {
 	}
 
 	s.ID = sub.ID
+	s.UserID = sub.UserID
 	s.TargetURL = sub.TargetURL
 	s.TargetCloud.LinkedCloudID = sub.TargetCloud.LinkedCloudID
 	s.TargetCloud.AccessToken = store.AccessToken(sub.TargetCloud.AccessToken)
 	s.TargetCloud.RefreshToken = sub.TargetCloud.RefreshToken
 	if sub.TargetCloud.Expiry != 0 {
-		s.TargetCloud.Expiry = time.Unix(-1, sub.TargetCloud.Expiry)
-	}
+		s.TargetCloud.Expiry = time.Unix(0, sub.TargetCloud.Expiry)
+	}
+	s.Status = store.LinkedAccountStatus(sub.Status)
+	s.LastError = sub.LastError
+	s.LastErrorAt = toTime(sub.LastErrorAt)
+	s.StatusChangedAt = toTime(sub.StatusChangedAt)
+	s.Version = uint64(sub.Version)
+	s.TargetReauthRequired = sub.TargetReauthRequired
 	s.OriginCloud.LinkedCloudID = sub.OriginCloud.LinkedCloudID
 	s.OriginCloud.AccessToken = store.AccessToken(sub.OriginCloud.AccessToken)
 	s.OriginCloud.RefreshToken = sub.OriginCloud.RefreshToken
 	if sub.OriginCloud.Expiry != 0 {
-		s.OriginCloud.Expiry = time.Unix(-1, sub.OriginCloud.Expiry)
+		s.OriginCloud.Expiry = time.Unix(0, sub.OriginCloud.Expiry)
 	}
 
 	return true
@@ -182,3 +210,17 @@ func (i *iterator) Next(ctx context.Context, s *store.LinkedAccount) bool {
 func (i *iterator) Err() error {
 	return i.iter.Err()
 }
+
+func makeDBTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func toTime(t int64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
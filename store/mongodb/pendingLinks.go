@@ -0,0 +1,79 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const pendingLinkCName = "pendingLinks"
+const expiryKey = "expiry"
+
+type dbPendingLink struct {
+	State  string    `bson:"_id"`
+	Data   []byte    `bson:"data"`
+	Expiry time.Time `bson:"expiry"`
+}
+
+// ensurePendingLinkTTLIndex lets MongoDB remove pending links whose callback never came.
+func ensurePendingLinkTTLIndex(ctx context.Context, col *mongo.Collection) error {
+	opts := &options.IndexOptions{}
+	opts.SetBackground(false)
+	opts.SetExpireAfterSeconds(0)
+	_, err := col.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: expiryKey, Value: 1}},
+		Options: opts,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot ensure ttl index for pending links: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) InsertPendingLink(ctx context.Context, link store.PendingLink) error {
+	err := link.Validate()
+	if err != nil {
+		return err
+	}
+
+	col := s.client.Database(s.DBName()).Collection(pendingLinkCName)
+	if _, err := col.InsertOne(ctx, dbPendingLink{
+		State:  link.State,
+		Data:   link.Data,
+		Expiry: link.Expiry,
+	}); err != nil {
+		return fmt.Errorf("cannot insert pending link: %v", err)
+	}
+	return nil
+}
+
+func (s *Store) PopPendingLink(ctx context.Context, state string) (store.PendingLink, error) {
+	col := s.client.Database(s.DBName()).Collection(pendingLinkCName)
+	res := col.FindOneAndDelete(ctx, bson.M{"_id": state})
+	if res.Err() == mongo.ErrNoDocuments {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: not found")
+	}
+	if res.Err() != nil {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: %v", res.Err())
+	}
+	var p dbPendingLink
+	err := res.Decode(&p)
+	if err != nil {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: %v", err)
+	}
+	link := store.PendingLink{
+		State:  p.State,
+		Data:   p.Data,
+		Expiry: p.Expiry,
+	}
+	// TTL monitor of MongoDB removes expired documents with a delay
+	if link.IsExpired() {
+		return store.PendingLink{}, fmt.Errorf("cannot pop pending link: expired")
+	}
+	return link, nil
+}
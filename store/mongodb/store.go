@@ -32,8 +32,9 @@ func NewStore(ctx context.Context, cfg Config) (*Store, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not dial database: %v", err)
 	}
-	ctx, _ = context.WithTimeout(ctx, 2*time.Second)
-	err = client.Ping(ctx, readpref.Primary())
+	pingCtx, pingCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer pingCancel()
+	err = client.Ping(pingCtx, readpref.Primary())
 	if err != nil {
 		return nil, fmt.Errorf("could not dial database: %v", err)
 	}
@@ -64,6 +65,19 @@ func NewStoreWithSession(ctx context.Context, client *mongo.Client, dbPrefix str
 		return nil, fmt.Errorf("cannot ensure index for device subscription: %v", err)
 	}
 
+	col = s.client.Database(s.DBName()).Collection(resLinkedAccountCName)
+	err = ensureIndex(ctx, col, linkedAccountUserIDQueryIndex, linkedAccountTargetLinkedCloudIDQueryIndex)
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("cannot ensure index for linked account: %v", err)
+	}
+
+	err = ensurePendingLinkTTLIndex(ctx, s.client.Database(s.DBName()).Collection(pendingLinkCName))
+	if err != nil {
+		client.Disconnect(ctx)
+		return nil, err
+	}
+
 	return s, nil
 }
 
@@ -105,6 +119,9 @@ func (s *Store) Clear(ctx context.Context) error {
 	if err := s.client.Database(s.DBName()).Collection(subscriptionCName).Drop(ctx); err != nil {
 		errors = append(errors, err)
 	}
+	if err := s.client.Database(s.DBName()).Collection(pendingLinkCName).Drop(ctx); err != nil {
+		errors = append(errors, err)
+	}
 	if len(errors) > 0 {
 		return fmt.Errorf("cannot clear: %v", errors)
 	}
@@ -116,3 +133,13 @@ func (s *Store) Clear(ctx context.Context) error {
 func (s *Store) Close(ctx context.Context) error {
 	return s.client.Disconnect(ctx)
 }
+
+const versionKey = "version"
+
+// versionFilter matches the version of a document. Documents stored before versioning have no version.
+func versionFilter(version uint64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{int64(0), nil}}
+	}
+	return int64(version)
+}
@@ -0,0 +1,26 @@
+package mongodb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/storetest"
+	"github.com/kelseyhightower/envconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (store.Store, func()) {
+		var config Config
+		err := envconfig.Process("", &config)
+		require.NoError(t, err)
+		ctx := context.Background()
+		s, err := NewStore(ctx, config)
+		require.NoError(t, err)
+		return s, func() {
+			s.Clear(ctx)
+			s.Close(ctx)
+		}
+	})
+}
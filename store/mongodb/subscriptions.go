@@ -3,6 +3,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"math"
 
 	"github.com/go-ocf/openapi-connector/store"
 	"go.mongodb.org/mongo-driver/bson"
@@ -11,48 +12,55 @@ import (
 )
 
 const subscriptionCName = "Subscription"
-const hrefKey = "resourcehref"
-const linkedAccountIDKey = "linkedAccountID"
+const hrefKey = "href"
+const linkedAccountIDKey = "linkedaccountid"
 const deviceIDKey = "deviceid"
 const signingSecretKey = "signingsecret"
 const typeKey = "type"
+const nextSequenceNumberKey = "nextsequencenumber"
+
+// maxSequenceNumber is the highest accepted sequence number. MongoDB doesn't support unsigned integers,
+// so NextSequenceNumber is stored as int64 and sequence numbers above it are rejected instead of overflowing.
+const maxSequenceNumber = math.MaxInt64 - 1
 
 var typeQueryIndex = bson.D{
-	{typeKey, 1},
+	{Key: typeKey, Value: 1},
 }
 
 var subscriptionLinkAccountQueryIndex = bson.D{
-	{linkedAccountIDKey, 1},
+	{Key: linkedAccountIDKey, Value: 1},
 }
 
 var subscriptionDeviceQueryIndex = bson.D{
-	{deviceIDKey, 1},
-	{typeKey, 1},
+	{Key: deviceIDKey, Value: 1},
+	{Key: typeKey, Value: 1},
 }
 
 var subscriptionDeviceHrefQueryIndex = bson.D{
-	{hrefKey, 1},
-	{deviceIDKey, 1},
-	{typeKey, 1},
+	{Key: hrefKey, Value: 1},
+	{Key: deviceIDKey, Value: 1},
+	{Key: typeKey, Value: 1},
 }
 
 type dbSubscription struct {
-	SubscriptionID  string `bson:"_id"`
-	LinkedAccountID string `bson:linkedAccountIDKey`
-	DeviceID        string `bson:deviceIDKey`
-	Href            string `bson:hrefKey`
-	Type            string `bson:typeKey`
-	SigningSecret   string `bson:signingSecretKey`
+	SubscriptionID     string `bson:"_id"`
+	LinkedAccountID    string `bson:"linkedaccountid"`
+	DeviceID           string `bson:"deviceid"`
+	Href               string `bson:"href"`
+	Type               string `bson:"type"`
+	SigningSecret      string `bson:"signingsecret"`
+	NextSequenceNumber int64  `bson:"nextsequencenumber"`
 }
 
 func makeDBSubscription(sub store.Subscription) dbSubscription {
 	return dbSubscription{
-		SubscriptionID:  sub.SubscriptionID,
-		LinkedAccountID: sub.LinkedAccountID,
-		DeviceID:        sub.DeviceID,
-		Href:            sub.Href,
-		Type:            string(sub.Type),
-		SigningSecret:   sub.SigningSecret,
+		SubscriptionID:     sub.SubscriptionID,
+		LinkedAccountID:    sub.LinkedAccountID,
+		DeviceID:           sub.DeviceID,
+		Href:               sub.Href,
+		Type:               string(sub.Type),
+		SigningSecret:      sub.SigningSecret,
+		NextSequenceNumber: int64(sub.NextSequenceNumber),
 	}
 }
 
@@ -62,6 +70,10 @@ func (s *Store) LoadSubscriptions(ctx context.Context, queries []store.Subscript
 	q := bson.M{}
 	bsonQueries := make([]bson.M, 0, 32)
 	for _, query := range queries {
+		err := query.Validate()
+		if err != nil {
+			return err
+		}
 		tmp := bson.M{}
 		if query.SubscriptionID != "" {
 			tmp["_id"] = query.SubscriptionID
@@ -75,19 +87,10 @@ func (s *Store) LoadSubscriptions(ctx context.Context, queries []store.Subscript
 			opts.SetHint(typeQueryIndex)
 		}
 		if query.DeviceID != "" {
-			if query.Type == "" {
-				return fmt.Errorf("cannot load device subscription: invalid Type")
-			}
 			tmp[deviceIDKey] = query.DeviceID
 			opts.SetHint(subscriptionDeviceQueryIndex)
 		}
 		if query.Href != "" {
-			if query.DeviceID == "" {
-				return fmt.Errorf("cannot load resource subscription: invalid DeviceID")
-			}
-			if query.Type == "" {
-				return fmt.Errorf("cannot load resource subscription: invalid Type")
-			}
 			tmp[hrefKey] = query.Href
 			opts.SetHint(subscriptionDeviceHrefQueryIndex)
 		}
@@ -117,11 +120,12 @@ func (s *Store) LoadSubscriptions(ctx context.Context, queries []store.Subscript
 }
 
 func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscription) (store.Subscription, error) {
-	if sub.SubscriptionID == "" {
-		return store.Subscription{}, fmt.Errorf("invalid SubscriptionID")
+	err := sub.Validate()
+	if err != nil {
+		return store.Subscription{}, err
 	}
-	if sub.LinkedAccountID == "" {
-		return store.Subscription{}, fmt.Errorf("invalid LinkedAccountID")
+	if sub.NextSequenceNumber > maxSequenceNumber+1 {
+		return store.Subscription{}, fmt.Errorf("cannot save subscription: NextSequenceNumber %v is out of range", sub.NextSequenceNumber)
 	}
 	q := bson.M{
 		//"_id": sub.SubscriptionID,
@@ -131,20 +135,9 @@ func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscrip
 		},
 	}
 	switch sub.Type {
-	case "":
-		return sub, fmt.Errorf("invalid Type")
 	case store.Type_Device:
-		if sub.DeviceID == "" {
-			return store.Subscription{}, fmt.Errorf("invalid DeviceID")
-		}
 		q[deviceIDKey] = sub.DeviceID
 	case store.Type_Resource:
-		if sub.DeviceID == "" {
-			return store.Subscription{}, fmt.Errorf("invalid DeviceID")
-		}
-		if sub.Href == "" {
-			return store.Subscription{}, fmt.Errorf("invalid Href")
-		}
 		q[deviceIDKey] = sub.DeviceID
 		q[hrefKey] = sub.Href
 	}
@@ -161,7 +154,7 @@ func (s *Store) FindOrCreateSubscription(ctx context.Context, sub store.Subscrip
 	}
 
 	var storedSub dbSubscription
-	err := res.Decode(&storedSub)
+	err = res.Decode(&storedSub)
 	if err != nil {
 		return store.Subscription{}, fmt.Errorf("cannot devcode all device subscription: %v", err)
 	}
@@ -185,7 +178,7 @@ func (s *Store) RemoveSubscriptions(ctx context.Context, query store.Subscriptio
 	q := bson.M{}
 	if query.SubscriptionID != "" {
 		q["_id"] = query.SubscriptionID
-	} else if query.LinkedAccountID == "" {
+	} else if query.LinkedAccountID != "" {
 		q[linkedAccountIDKey] = query.LinkedAccountID
 	}
 	_, err := s.client.Database(s.DBName()).Collection(subscriptionCName).DeleteMany(ctx, q)
@@ -195,6 +188,48 @@ func (s *Store) RemoveSubscriptions(ctx context.Context, query store.Subscriptio
 	return nil
 }
 
+func (s *Store) UpdateSubscriptionSequenceNumber(ctx context.Context, subscriptionID string, sequenceNumber uint64) error {
+	if sequenceNumber > maxSequenceNumber {
+		return fmt.Errorf("cannot update sequence number: %v is out of range", sequenceNumber)
+	}
+	col := s.client.Database(s.DBName()).Collection(subscriptionCName)
+	// subscriptions stored before sequence numbers were tracked don't have the key
+	q := bson.M{
+		"_id": subscriptionID,
+		"$or": []bson.M{
+			{nextSequenceNumberKey: bson.M{"$lte": int64(sequenceNumber)}},
+			{nextSequenceNumberKey: bson.M{"$exists": false}},
+		},
+	}
+	res, err := col.UpdateOne(ctx, q, bson.M{"$set": bson.M{nextSequenceNumberKey: int64(sequenceNumber) + 1}})
+	if err != nil {
+		return fmt.Errorf("cannot update sequence number: %v", err)
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	count, err := col.CountDocuments(ctx, bson.M{"_id": subscriptionID})
+	if err != nil {
+		return fmt.Errorf("cannot update sequence number: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("cannot update sequence number: subscription %v not found", subscriptionID)
+	}
+	return store.StaleSequenceNumberError{SubscriptionID: subscriptionID, SequenceNumber: sequenceNumber}
+}
+
+func (s *Store) UpdateSubscriptionSigningSecret(ctx context.Context, subscriptionID string, signingSecret string) error {
+	col := s.client.Database(s.DBName()).Collection(subscriptionCName)
+	res, err := col.UpdateOne(ctx, bson.M{"_id": subscriptionID}, bson.M{"$set": bson.M{signingSecretKey: signingSecret}})
+	if err != nil {
+		return fmt.Errorf("cannot update signing secret: %v", err)
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("cannot update signing secret: subscription %v not found", subscriptionID)
+	}
+	return nil
+}
+
 type subscriptionIterator struct {
 	iter *mongo.Cursor
 }
@@ -220,6 +255,7 @@ func (i *subscriptionIterator) Next(ctx context.Context, s *store.Subscription)
 	s.Href = sub.Href
 	s.Type = store.Type(sub.Type)
 	s.SigningSecret = sub.SigningSecret
+	s.NextSequenceNumber = uint64(sub.NextSequenceNumber)
 
 	return true
 }
@@ -2,6 +2,7 @@ package mongodb
 
 import (
 	"context"
+	"math"
 	"testing"
 
 	"github.com/go-ocf/openapi-connector/store"
@@ -389,3 +390,18 @@ func TestStore_RemoveSubscriptions(t *testing.T) {
 		})
 	}
 }
+
+func TestStore_UpdateSubscriptionSequenceNumberOutOfRange(t *testing.T) {
+	// the sequence number is validated before the DB is reached
+	var s Store
+	err := s.UpdateSubscriptionSequenceNumber(context.Background(), "testSubscriptionID", math.MaxInt64)
+	require.Error(t, err)
+	assert.False(t, store.IsStaleSequenceNumberError(err))
+	_, err = s.FindOrCreateSubscription(context.Background(), store.Subscription{
+		SubscriptionID:     "testSubscriptionID",
+		Type:               store.Type_Devices,
+		LinkedAccountID:    "testLinkedAccountID",
+		NextSequenceNumber: math.MaxUint64,
+	})
+	require.Error(t, err)
+}
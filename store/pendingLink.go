@@ -0,0 +1,31 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+// PendingLink keeps the state of an unfinished account linking between the OAuth authorization request and its callback.
+type PendingLink struct {
+	// State is the OAuth state parameter which identifies the pending link.
+	State string
+	// Data is opaque for the store.
+	Data   []byte
+	Expiry time.Time
+}
+
+// IsExpired reports whether the pending link cannot be used anymore.
+func (p PendingLink) IsExpired() bool {
+	return !p.Expiry.After(time.Now())
+}
+
+// Validate checks that the pending link can be stored.
+func (p PendingLink) Validate() error {
+	if p.State == "" {
+		return fmt.Errorf("cannot save pending link: invalid State")
+	}
+	if p.Expiry.IsZero() {
+		return fmt.Errorf("cannot save pending link: invalid Expiry")
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// Revoke revokes the token at the revocation endpoint (RFC 7009) of the linked cloud which issued it.
+// The refresh token is revoked when it is set, because the authorization server revokes access tokens
+// of the grant with it, otherwise the access token is revoked. Nothing is revoked when the linked cloud
+// doesn't provide the revocation endpoint.
+func (o OAuth) Revoke(ctx context.Context, l LinkedCloud) error {
+	if l.Endpoint.RevocationUrl == "" {
+		return nil
+	}
+	token, hint := o.RefreshToken, "refresh_token"
+	if token == "" {
+		token, hint = string(o.AccessToken), "access_token"
+	}
+	if token == "" {
+		return nil
+	}
+	v := url.Values{}
+	v.Set("token", token)
+	v.Set("token_type_hint", hint)
+	// the client authenticates like at the token endpoint
+	resp, err := l.postForm(ctx, l.Endpoint.RevocationUrl, v)
+	if err != nil {
+		return fmt.Errorf("cannot revoke token: %v", err)
+	}
+	defer resp.Body.Close()
+	// the token is also revoked when the server responds 200 for an unknown token
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("cannot revoke token: unexpected statusCode %v: %s", resp.StatusCode, body)
+	}
+	return nil
+}
+
+// RevokeTokens revokes tokens of the target and the origin cloud. Both revocations are attempted,
+// the returned error contains all failures.
+func (l LinkedAccount) RevokeTokens(ctx context.Context, s Store, originCloud LinkedCloud) error {
+	var errors []error
+	targetCloud, err := l.LoadTargetCloud(ctx, s)
+	if err == nil {
+		err = l.TargetCloud.Revoke(ctx, targetCloud)
+	}
+	if err != nil {
+		errors = append(errors, fmt.Errorf("cannot revoke target cloud token: %v", err))
+	}
+	err = l.OriginCloud.Revoke(ctx, originCloud)
+	if err != nil {
+		errors = append(errors, fmt.Errorf("cannot revoke origin cloud token: %v", err))
+	}
+	if len(errors) > 0 {
+		return fmt.Errorf("%v", errors)
+	}
+	return nil
+}
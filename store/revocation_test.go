@@ -0,0 +1,150 @@
+package store_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/go-ocf/openapi-connector/store/inmemory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type revokedToken struct {
+	clientID string
+	// basic is set when the client authenticated by HTTP Basic authentication instead of the form
+	basic bool
+	token string
+	hint  string
+}
+
+func newTestRevocationServer(t *testing.T, revoked *[]revokedToken) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		clientID, clientSecret, basic := r.BasicAuth()
+		if !basic {
+			clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+		}
+		if r.PostForm.Get("token") == "unavailableToken" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if clientSecret != "" && clientSecret != "testClientSecret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		*revoked = append(*revoked, revokedToken{clientID: clientID, basic: basic, token: r.PostForm.Get("token"), hint: r.PostForm.Get("token_type_hint")})
+	}))
+}
+
+func TestOAuthRevoke(t *testing.T) {
+	var revoked []revokedToken
+	server := newTestRevocationServer(t, &revoked)
+	defer server.Close()
+	cloud := store.LinkedCloud{
+		ClientID: "testClientID",
+		Endpoint: store.Endpoint{RevocationUrl: server.URL},
+	}
+
+	tests := []struct {
+		name    string
+		oauth   store.OAuth
+		cloud   store.LinkedCloud
+		want    []revokedToken
+		wantErr bool
+	}{
+		{
+			name:  "refresh token",
+			oauth: store.OAuth{AccessToken: "testAccessToken", RefreshToken: "testRefreshToken"},
+			cloud: cloud,
+			want:  []revokedToken{{clientID: "testClientID", token: "testRefreshToken", hint: "refresh_token"}},
+		},
+		{
+			name:  "access token",
+			oauth: store.OAuth{AccessToken: "testAccessToken"},
+			cloud: cloud,
+			want:  []revokedToken{{clientID: "testClientID", token: "testAccessToken", hint: "access_token"}},
+		},
+		{
+			name:  "client secret in header",
+			oauth: store.OAuth{RefreshToken: "testRefreshToken"},
+			cloud: store.LinkedCloud{
+				ClientID:     "testClientID",
+				ClientSecret: "testClientSecret",
+				Endpoint:     store.Endpoint{RevocationUrl: server.URL, AuthStyle: store.AuthStyle_HEADER},
+			},
+			want: []revokedToken{{clientID: "testClientID", basic: true, token: "testRefreshToken", hint: "refresh_token"}},
+		},
+		{
+			name:  "client secret in params",
+			oauth: store.OAuth{RefreshToken: "testRefreshToken"},
+			cloud: store.LinkedCloud{
+				ClientID:     "testClientID",
+				ClientSecret: "testClientSecret",
+				Endpoint:     store.Endpoint{RevocationUrl: server.URL, AuthStyle: store.AuthStyle_PARAMS},
+			},
+			want: []revokedToken{{clientID: "testClientID", token: "testRefreshToken", hint: "refresh_token"}},
+		},
+		{
+			name:  "without revocation endpoint",
+			oauth: store.OAuth{AccessToken: "testAccessToken", RefreshToken: "testRefreshToken"},
+		},
+		{
+			name:    "unavailable",
+			oauth:   store.OAuth{RefreshToken: "unavailableToken"},
+			cloud:   cloud,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			revoked = nil
+			err := tt.oauth.Revoke(context.Background(), tt.cloud)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, revoked)
+		})
+	}
+}
+
+func TestRevokeTokens(t *testing.T) {
+	var revoked []revokedToken
+	server := newTestRevocationServer(t, &revoked)
+	defer server.Close()
+
+	ctx := context.Background()
+	s := inmemory.NewStore()
+	require.NoError(t, s.InsertLinkedCloud(ctx, store.LinkedCloud{
+		ID:           "testLinkedCloudID",
+		ClientID:     "testTargetClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope"},
+		Endpoint:     store.Endpoint{AuthUrl: server.URL, TokenUrl: server.URL, RevocationUrl: server.URL},
+	}))
+	originCloud := store.LinkedCloud{
+		ClientID: "testOriginClientID",
+		Endpoint: store.Endpoint{RevocationUrl: server.URL},
+	}
+	l := store.LinkedAccount{
+		ID:          "testID",
+		TargetCloud: store.OAuth{LinkedCloudID: "testLinkedCloudID", RefreshToken: "unavailableToken"},
+		OriginCloud: store.OAuth{RefreshToken: "originRefreshToken"},
+	}
+
+	err := l.RevokeTokens(ctx, s, originCloud)
+	require.Error(t, err)
+	assert.Equal(t, []revokedToken{{clientID: "testOriginClientID", token: "originRefreshToken", hint: "refresh_token"}}, revoked)
+
+	revoked = nil
+	l.TargetCloud.RefreshToken = "targetRefreshToken"
+	require.NoError(t, l.RevokeTokens(ctx, s, originCloud))
+	assert.Equal(t, []revokedToken{
+		{clientID: "testTargetClientID", basic: true, token: "targetRefreshToken", hint: "refresh_token"},
+		{clientID: "testOriginClientID", token: "originRefreshToken", hint: "refresh_token"},
+	}, revoked)
+}
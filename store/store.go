@@ -2,10 +2,50 @@ package store
 
 import (
 	"context"
+	"fmt"
 )
 
+// ConflictError is returned by updates when the stored version differs from the version of the update,
+// because the record was modified since it was loaded.
+type ConflictError struct {
+	// Kind of the record, e.g. "linked account".
+	Kind string
+	ID   string
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("cannot update %v %v: version conflict", e.Kind, e.ID)
+}
+
+// IsConflictError reports whether the err is ConflictError.
+func IsConflictError(err error) bool {
+	_, ok := err.(ConflictError)
+	return ok
+}
+
+// StaleSequenceNumberError is returned by UpdateSubscriptionSequenceNumber when the sequence number
+// was already accepted or it is lower than an accepted one.
+type StaleSequenceNumberError struct {
+	SubscriptionID string
+	SequenceNumber uint64
+}
+
+func (e StaleSequenceNumberError) Error() string {
+	return fmt.Sprintf("cannot accept sequence number %v of subscription %v: stale or duplicate event", e.SequenceNumber, e.SubscriptionID)
+}
+
+// IsStaleSequenceNumberError reports whether the err is StaleSequenceNumberError.
+func IsStaleSequenceNumberError(err error) bool {
+	_, ok := err.(StaleSequenceNumberError)
+	return ok
+}
+
 type Query struct {
 	ID string
+	// UserID filters linked accounts by the owner. It is ignored by linked clouds.
+	UserID string
+	// LinkedCloudID filters linked accounts by the linked cloud of the target cloud. It is ignored by linked clouds.
+	LinkedCloudID string
 }
 
 type LinkedAccountIter interface {
@@ -34,6 +74,22 @@ type SubscriptionQuery struct {
 	Type            Type
 }
 
+// Validate checks that Href is used only with DeviceID and DeviceID only with Type.
+func (q SubscriptionQuery) Validate() error {
+	if q.DeviceID != "" && q.Type == "" {
+		return fmt.Errorf("cannot load device subscription: invalid Type")
+	}
+	if q.Href != "" {
+		if q.DeviceID == "" {
+			return fmt.Errorf("cannot load resource subscription: invalid DeviceID")
+		}
+		if q.Type == "" {
+			return fmt.Errorf("cannot load resource subscription: invalid Type")
+		}
+	}
+	return nil
+}
+
 type SubscriptionIter interface {
 	Next(ctx context.Context, sub *Subscription) bool
 	Err() error
@@ -44,11 +100,15 @@ type SubscriptionHandler interface {
 }
 
 type Store interface {
+	// UpdateLinkedCloud stores the linked cloud when the stored Version equals sub.Version and increments the stored Version.
+	// It returns ConflictError when the versions differ.
 	UpdateLinkedCloud(ctx context.Context, sub LinkedCloud) error
 	InsertLinkedCloud(ctx context.Context, sub LinkedCloud) error
 	RemoveLinkedCloud(ctx context.Context, ConfigId string) error
 	LoadLinkedClouds(ctx context.Context, query Query, h LinkedCloudHandler) error
 
+	// UpdateLinkedAccount stores the linked account when the stored Version equals sub.Version and increments the stored Version.
+	// It returns ConflictError when the versions differ.
 	UpdateLinkedAccount(ctx context.Context, sub LinkedAccount) error
 	InsertLinkedAccount(ctx context.Context, sub LinkedAccount) error
 	RemoveLinkedAccount(ctx context.Context, LinkedAccountId string) error
@@ -57,4 +117,15 @@ type Store interface {
 	LoadSubscriptions(ctx context.Context, query []SubscriptionQuery, h SubscriptionHandler) error
 	FindOrCreateSubscription(ctx context.Context, sub Subscription) (Subscription, error)
 	RemoveSubscriptions(ctx context.Context, query SubscriptionQuery) error
+	// UpdateSubscriptionSequenceNumber accepts the sequence number of an event of the subscription by setting the stored
+	// NextSequenceNumber to sequenceNumber+1. It returns StaleSequenceNumberError when sequenceNumber is lower than the stored
+	// NextSequenceNumber, so each sequence number is accepted once.
+	UpdateSubscriptionSequenceNumber(ctx context.Context, subscriptionID string, sequenceNumber uint64) error
+	// UpdateSubscriptionSigningSecret replaces the stored SigningSecret of the subscription, other fields are kept.
+	UpdateSubscriptionSigningSecret(ctx context.Context, subscriptionID string, signingSecret string) error
+
+	// InsertPendingLink stores the pending link. It fails when a pending link with the same State exists.
+	InsertPendingLink(ctx context.Context, link PendingLink) error
+	// PopPendingLink returns and removes the pending link. It fails when the pending link is not found or it is expired.
+	PopPendingLink(ctx context.Context, state string) (PendingLink, error)
 }
@@ -0,0 +1,274 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLinkedAccount(id string) store.LinkedAccount {
+	return store.LinkedAccount{
+		ID:        id,
+		UserID:    "testUserID",
+		TargetURL: "testTargetURL",
+		TargetCloud: store.OAuth{
+			LinkedCloudID: "testLinkedCloudID",
+			AccessToken:   "testAccessToken",
+			RefreshToken:  "testRefreshToken",
+			Expiry:        time.Unix(1600000000, 0),
+		},
+		OriginCloud: store.OAuth{
+			LinkedCloudID: "testOriginLinkedCloudID",
+			AccessToken:   "testOriginAccessToken",
+			RefreshToken:  "testOriginRefreshToken",
+			Expiry:        time.Unix(1600000001, 0),
+		},
+	}
+}
+
+func testInsertLinkedAccount(t *testing.T, newStore NewStoreFunc) {
+	withoutTokens := newTestLinkedAccount("testID")
+	withoutTokens.TargetCloud.AccessToken = ""
+	withoutTokens.TargetCloud.RefreshToken = ""
+	withoutTargetURL := newTestLinkedAccount("testID")
+	withoutTargetURL.TargetURL = ""
+	withoutLinkedCloudID := newTestLinkedAccount("testID")
+	withoutLinkedCloudID.TargetCloud.LinkedCloudID = ""
+
+	tests := []struct {
+		name    string
+		sub     store.LinkedAccount
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sub:  newTestLinkedAccount("testID"),
+		},
+		{
+			name:    "duplicit ID",
+			sub:     newTestLinkedAccount("testID"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid ID",
+			sub:     newTestLinkedAccount(""),
+			wantErr: true,
+		},
+		{
+			name:    "invalid tokens",
+			sub:     withoutTokens,
+			wantErr: true,
+		},
+		{
+			name:    "invalid TargetURL",
+			sub:     withoutTargetURL,
+			wantErr: true,
+		},
+		{
+			name:    "invalid LinkedCloudID",
+			sub:     withoutLinkedCloudID,
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.InsertLinkedAccount(ctx, tt.sub)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, []store.LinkedAccount{newTestLinkedAccount("testID")}, loadLinkedAccounts(ctx, t, s, store.Query{}))
+}
+
+func testUpdateLinkedAccount(t *testing.T, newStore NewStoreFunc) {
+	updated := newTestLinkedAccount("testID")
+	updated.TargetCloud.AccessToken = "testAccessTokenUpdated"
+	updated.TargetCloud.Expiry = time.Unix(1700000000, 0)
+	updated.OriginCloud.Expiry = time.Time{}
+	updated.UserID = "testUserIDUpdated"
+	updated.Status = store.LinkedAccountStatus_REAUTH_REQUIRED
+	updated.LastError = "testLastError"
+	updated.LastErrorAt = time.Unix(1700000001, 0)
+	updated.StatusChangedAt = time.Unix(1700000002, 0)
+	updated.TargetReauthRequired = true
+	invalid := newTestLinkedAccount("testID")
+	invalid.TargetURL = ""
+
+	tests := []struct {
+		name    string
+		sub     store.LinkedAccount
+		wantErr bool
+	}{
+		{
+			name:    "not found",
+			sub:     newTestLinkedAccount("testID1"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid TargetURL",
+			sub:     invalid,
+			wantErr: true,
+		},
+		{
+			name: "valid",
+			sub:  updated,
+		},
+		{
+			name:    "conflict",
+			sub:     updated,
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	require.NoError(t, s.InsertLinkedAccount(ctx, newTestLinkedAccount("testID")))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateLinkedAccount(ctx, tt.sub)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	err := s.UpdateLinkedAccount(ctx, updated)
+	assert.True(t, store.IsConflictError(err))
+	updated.Version = 1
+	assert.Equal(t, []store.LinkedAccount{updated}, loadLinkedAccounts(ctx, t, s, store.Query{}))
+	assert.Empty(t, loadLinkedAccounts(ctx, t, s, store.Query{UserID: "testUserID"}))
+	assert.Equal(t, []store.LinkedAccount{updated}, loadLinkedAccounts(ctx, t, s, store.Query{UserID: updated.UserID}))
+}
+
+func testRemoveLinkedAccount(t *testing.T, newStore NewStoreFunc) {
+	tests := []struct {
+		name            string
+		linkedAccountID string
+		wantErr         bool
+	}{
+		{
+			name:            "invalid ID",
+			linkedAccountID: "",
+			wantErr:         true,
+		},
+		{
+			name:            "not found",
+			linkedAccountID: "testNotFound",
+			wantErr:         true,
+		},
+		{
+			name:            "valid",
+			linkedAccountID: "testID",
+		},
+		{
+			name:            "already removed",
+			linkedAccountID: "testID",
+			wantErr:         true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	require.NoError(t, s.InsertLinkedAccount(ctx, newTestLinkedAccount("testID")))
+	require.NoError(t, s.InsertLinkedAccount(ctx, newTestLinkedAccount("testID2")))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.RemoveLinkedAccount(ctx, tt.linkedAccountID)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, []store.LinkedAccount{newTestLinkedAccount("testID2")}, loadLinkedAccounts(ctx, t, s, store.Query{}))
+}
+
+func testLoadLinkedAccounts(t *testing.T, newStore NewStoreFunc) {
+	linkedAccounts := []store.LinkedAccount{
+		newTestLinkedAccount("testID"),
+		newTestLinkedAccount("testID2"),
+		newTestLinkedAccount("testID3"),
+	}
+	linkedAccounts[1].TargetCloud.Expiry = time.Time{}
+	linkedAccounts[1].OriginCloud.RefreshToken = ""
+	linkedAccounts[2].UserID = "testUserID2"
+	linkedAccounts[2].TargetCloud.LinkedCloudID = "testLinkedCloudID2"
+
+	tests := []struct {
+		name  string
+		query store.Query
+		want  []store.LinkedAccount
+	}{
+		{
+			name:  "all",
+			query: store.Query{},
+			want:  linkedAccounts,
+		},
+		{
+			name:  "id",
+			query: store.Query{ID: linkedAccounts[1].ID},
+			want:  []store.LinkedAccount{linkedAccounts[1]},
+		},
+		{
+			name:  "userID",
+			query: store.Query{UserID: "testUserID"},
+			want:  linkedAccounts[:2],
+		},
+		{
+			name:  "id and userID",
+			query: store.Query{ID: linkedAccounts[2].ID, UserID: "testUserID2"},
+			want:  []store.LinkedAccount{linkedAccounts[2]},
+		},
+		{
+			name:  "linkedCloudID",
+			query: store.Query{LinkedCloudID: "testLinkedCloudID2"},
+			want:  []store.LinkedAccount{linkedAccounts[2]},
+		},
+		{
+			name:  "userID and linkedCloudID",
+			query: store.Query{UserID: "testUserID", LinkedCloudID: "testLinkedCloudID2"},
+		},
+		{
+			name:  "id of another user",
+			query: store.Query{ID: linkedAccounts[2].ID, UserID: "testUserID"},
+		},
+		{
+			name:  "not found",
+			query: store.Query{ID: "not found"},
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, a := range linkedAccounts {
+		require.NoError(t, s.InsertLinkedAccount(ctx, a))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, loadLinkedAccounts(ctx, t, s, tt.query))
+		})
+	}
+}
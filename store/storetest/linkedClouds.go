@@ -0,0 +1,247 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLinkedCloud(id string) store.LinkedCloud {
+	return store.LinkedCloud{
+		ID:           id,
+		Name:         "testName",
+		ClientID:     "testClientID",
+		ClientSecret: "testClientSecret",
+		Scopes:       []string{"testScope1", "testScope2"},
+		Audience:     "testAudience",
+		AuthParams:   map[string]string{"prompt": "consent", "resource": "testResource"},
+		TokenParams:  map[string]string{"resource": "testResource"},
+		JwksURL:      "testJwksURL",
+		Issuer:       "testIssuer",
+		PKCE:         true,
+		GrantType:    store.GrantType_CLIENT_CREDENTIALS,
+		HTTP: store.HTTPConfig{
+			Timeout:            time.Second,
+			RootCAs:            "testRootCAs",
+			Certificate:        "testCertificate",
+			PrivateKey:         "testPrivateKey",
+			Proxy:              "testProxy",
+			InsecureSkipVerify: true,
+		},
+		Endpoint: store.Endpoint{
+			AuthUrl:                "testAuthUrl",
+			TokenUrl:               "testTokenUrl",
+			RevocationUrl:          "testRevocationUrl",
+			DeviceAuthorizationUrl: "testDeviceAuthorizationUrl",
+			AuthStyle:              store.AuthStyle_PARAMS,
+		},
+	}
+}
+
+func testInsertLinkedCloud(t *testing.T, newStore NewStoreFunc) {
+	withoutScopes := newTestLinkedCloud("testID")
+	withoutScopes.Scopes = nil
+	withoutAuthUrl := newTestLinkedCloud("testID")
+	withoutAuthUrl.Endpoint.AuthUrl = ""
+
+	tests := []struct {
+		name    string
+		sub     store.LinkedCloud
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			sub:  newTestLinkedCloud("testID"),
+		},
+		{
+			name:    "duplicit ID",
+			sub:     newTestLinkedCloud("testID"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid ID",
+			sub:     newTestLinkedCloud(""),
+			wantErr: true,
+		},
+		{
+			name:    "invalid Scopes",
+			sub:     withoutScopes,
+			wantErr: true,
+		},
+		{
+			name:    "invalid AuthUrl",
+			sub:     withoutAuthUrl,
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.InsertLinkedCloud(ctx, tt.sub)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, []store.LinkedCloud{newTestLinkedCloud("testID")}, loadLinkedClouds(ctx, t, s, store.Query{}))
+}
+
+func testUpdateLinkedCloud(t *testing.T, newStore NewStoreFunc) {
+	updated := newTestLinkedCloud("testID")
+	updated.Name = "testNameUpdated"
+	updated.Scopes = []string{"testScopeUpdated"}
+	invalid := newTestLinkedCloud("testID")
+	invalid.ClientSecret = ""
+
+	tests := []struct {
+		name    string
+		sub     store.LinkedCloud
+		wantErr bool
+	}{
+		{
+			name:    "not found",
+			sub:     newTestLinkedCloud("testIDnotFound"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid ClientSecret",
+			sub:     invalid,
+			wantErr: true,
+		},
+		{
+			name: "valid",
+			sub:  updated,
+		},
+		{
+			name:    "conflict",
+			sub:     updated,
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	require.NoError(t, s.InsertLinkedCloud(ctx, newTestLinkedCloud("testID")))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateLinkedCloud(ctx, tt.sub)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	err := s.UpdateLinkedCloud(ctx, updated)
+	assert.True(t, store.IsConflictError(err))
+	updated.Version = 1
+	assert.Equal(t, []store.LinkedCloud{updated}, loadLinkedClouds(ctx, t, s, store.Query{}))
+}
+
+func testRemoveLinkedCloud(t *testing.T, newStore NewStoreFunc) {
+	tests := []struct {
+		name          string
+		linkedCloudID string
+		wantErr       bool
+	}{
+		{
+			name:          "invalid ID",
+			linkedCloudID: "",
+			wantErr:       true,
+		},
+		{
+			name:          "not found",
+			linkedCloudID: "notFound",
+			wantErr:       true,
+		},
+		{
+			name:          "valid",
+			linkedCloudID: "testID",
+		},
+		{
+			name:          "already removed",
+			linkedCloudID: "testID",
+			wantErr:       true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	require.NoError(t, s.InsertLinkedCloud(ctx, newTestLinkedCloud("testID")))
+	require.NoError(t, s.InsertLinkedCloud(ctx, newTestLinkedCloud("testID2")))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.RemoveLinkedCloud(ctx, tt.linkedCloudID)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.Equal(t, []store.LinkedCloud{newTestLinkedCloud("testID2")}, loadLinkedClouds(ctx, t, s, store.Query{}))
+}
+
+func testLoadLinkedClouds(t *testing.T, newStore NewStoreFunc) {
+	lcs := []store.LinkedCloud{
+		newTestLinkedCloud("testID"),
+		newTestLinkedCloud("testID2"),
+	}
+	lcs[1].Audience = ""
+	lcs[1].AuthParams = nil
+	lcs[1].TokenParams = nil
+	lcs[1].JwksURL = ""
+	lcs[1].PKCE = false
+	lcs[1].GrantType = ""
+
+	tests := []struct {
+		name  string
+		query store.Query
+		want  []store.LinkedCloud
+	}{
+		{
+			name:  "all",
+			query: store.Query{},
+			want:  lcs,
+		},
+		{
+			name:  "id",
+			query: store.Query{ID: lcs[1].ID},
+			want:  []store.LinkedCloud{lcs[1]},
+		},
+		{
+			name:  "not found",
+			query: store.Query{ID: "not found"},
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, l := range lcs {
+		require.NoError(t, s.InsertLinkedCloud(ctx, l))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.want, loadLinkedClouds(ctx, t, s, tt.query))
+		})
+	}
+}
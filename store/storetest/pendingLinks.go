@@ -0,0 +1,101 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPendingLink(state string) store.PendingLink {
+	return store.PendingLink{
+		State:  state,
+		Data:   []byte("testData"),
+		Expiry: time.Unix(time.Now().Add(time.Hour).Unix(), 0),
+	}
+}
+
+func requirePendingLinkEqual(t *testing.T, want, got store.PendingLink) {
+	assert.Equal(t, want.State, got.State)
+	assert.Equal(t, want.Data, got.Data)
+	assert.True(t, want.Expiry.Equal(got.Expiry), "expiry %v != %v", want.Expiry, got.Expiry)
+}
+
+func testInsertPendingLink(t *testing.T, newStore NewStoreFunc) {
+	withoutState := newTestPendingLink("")
+	withoutExpiry := newTestPendingLink("testState")
+	withoutExpiry.Expiry = time.Time{}
+
+	tests := []struct {
+		name    string
+		link    store.PendingLink
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			link: newTestPendingLink("testState"),
+		},
+		{
+			name:    "duplicit",
+			link:    newTestPendingLink("testState"),
+			wantErr: true,
+		},
+		{
+			name:    "invalid State",
+			link:    withoutState,
+			wantErr: true,
+		},
+		{
+			name:    "invalid Expiry",
+			link:    withoutExpiry,
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.InsertPendingLink(ctx, tt.link)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func testPopPendingLink(t *testing.T, newStore NewStoreFunc) {
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	link := newTestPendingLink("testState")
+	expired := newTestPendingLink("testExpiredState")
+	expired.Expiry = time.Unix(time.Now().Add(-time.Hour).Unix(), 0)
+	require.NoError(t, s.InsertPendingLink(ctx, link))
+	require.NoError(t, s.InsertPendingLink(ctx, expired))
+
+	_, err := s.PopPendingLink(ctx, "notFound")
+	assert.Error(t, err)
+
+	_, err = s.PopPendingLink(ctx, expired.State)
+	assert.Error(t, err)
+
+	got, err := s.PopPendingLink(ctx, link.State)
+	require.NoError(t, err)
+	requirePendingLinkEqual(t, link, got)
+
+	// the pending link can be used only once
+	_, err = s.PopPendingLink(ctx, link.State)
+	assert.Error(t, err)
+
+	// the state can be reused after the pending link was popped
+	assert.NoError(t, s.InsertPendingLink(ctx, link))
+}
@@ -0,0 +1,93 @@
+// Package storetest provides a conformance test suite for store.Store implementations.
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/require"
+)
+
+// NewStoreFunc creates an empty store for a single test. The returned cleanUp
+// function is called when the test finishes.
+type NewStoreFunc func(t *testing.T) (s store.Store, cleanUp func())
+
+// Run runs the whole conformance suite against stores created by newStore.
+func Run(t *testing.T, newStore NewStoreFunc) {
+	t.Run("InsertLinkedCloud", func(t *testing.T) { testInsertLinkedCloud(t, newStore) })
+	t.Run("UpdateLinkedCloud", func(t *testing.T) { testUpdateLinkedCloud(t, newStore) })
+	t.Run("RemoveLinkedCloud", func(t *testing.T) { testRemoveLinkedCloud(t, newStore) })
+	t.Run("LoadLinkedClouds", func(t *testing.T) { testLoadLinkedClouds(t, newStore) })
+
+	t.Run("InsertLinkedAccount", func(t *testing.T) { testInsertLinkedAccount(t, newStore) })
+	t.Run("UpdateLinkedAccount", func(t *testing.T) { testUpdateLinkedAccount(t, newStore) })
+	t.Run("RemoveLinkedAccount", func(t *testing.T) { testRemoveLinkedAccount(t, newStore) })
+	t.Run("LoadLinkedAccounts", func(t *testing.T) { testLoadLinkedAccounts(t, newStore) })
+
+	t.Run("FindOrCreateSubscription", func(t *testing.T) { testFindOrCreateSubscription(t, newStore) })
+	t.Run("LoadSubscriptions", func(t *testing.T) { testLoadSubscriptions(t, newStore) })
+	t.Run("RemoveSubscriptions", func(t *testing.T) { testRemoveSubscriptions(t, newStore) })
+	t.Run("UpdateSubscriptionSequenceNumber", func(t *testing.T) { testUpdateSubscriptionSequenceNumber(t, newStore) })
+	t.Run("UpdateSubscriptionSigningSecret", func(t *testing.T) { testUpdateSubscriptionSigningSecret(t, newStore) })
+
+	t.Run("InsertPendingLink", func(t *testing.T) { testInsertPendingLink(t, newStore) })
+	t.Run("PopPendingLink", func(t *testing.T) { testPopPendingLink(t, newStore) })
+}
+
+type linkedCloudHandler struct {
+	lcs []store.LinkedCloud
+}
+
+func (h *linkedCloudHandler) Handle(ctx context.Context, iter store.LinkedCloudIter) (err error) {
+	var l store.LinkedCloud
+	for iter.Next(ctx, &l) {
+		h.lcs = append(h.lcs, l)
+	}
+	return iter.Err()
+}
+
+type linkedAccountHandler struct {
+	accs []store.LinkedAccount
+}
+
+func (h *linkedAccountHandler) Handle(ctx context.Context, iter store.LinkedAccountIter) (err error) {
+	var l store.LinkedAccount
+	for iter.Next(ctx, &l) {
+		h.accs = append(h.accs, l)
+	}
+	return iter.Err()
+}
+
+type subscriptionHandler struct {
+	subs []store.Subscription
+}
+
+func (h *subscriptionHandler) Handle(ctx context.Context, iter store.SubscriptionIter) (err error) {
+	var sub store.Subscription
+	for iter.Next(ctx, &sub) {
+		h.subs = append(h.subs, sub)
+	}
+	return iter.Err()
+}
+
+func loadLinkedClouds(ctx context.Context, t *testing.T, s store.Store, query store.Query) []store.LinkedCloud {
+	var h linkedCloudHandler
+	err := s.LoadLinkedClouds(ctx, query, &h)
+	require.NoError(t, err)
+	return h.lcs
+}
+
+func loadLinkedAccounts(ctx context.Context, t *testing.T, s store.Store, query store.Query) []store.LinkedAccount {
+	var h linkedAccountHandler
+	err := s.LoadLinkedAccounts(ctx, query, &h)
+	require.NoError(t, err)
+	return h.accs
+}
+
+func loadSubscriptions(ctx context.Context, t *testing.T, s store.Store, queries ...store.SubscriptionQuery) []store.Subscription {
+	var h subscriptionHandler
+	err := s.LoadSubscriptions(ctx, queries, &h)
+	require.NoError(t, err)
+	return h.subs
+}
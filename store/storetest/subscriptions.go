@@ -0,0 +1,402 @@
+package storetest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-ocf/openapi-connector/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSubscriptions() []store.Subscription {
+	return []store.Subscription{
+		store.Subscription{
+			SubscriptionID:  "0",
+			Type:            store.Type_Devices,
+			LinkedAccountID: "testLinkedAccountID",
+			SigningSecret:   "testSigningSecret0",
+		},
+		store.Subscription{
+			SubscriptionID:  "1",
+			Type:            store.Type_Device,
+			LinkedAccountID: "testLinkedAccountID",
+			DeviceID:        "testDeviceID",
+			SigningSecret:   "testSigningSecret1",
+		},
+		store.Subscription{
+			SubscriptionID:  "2",
+			Type:            store.Type_Resource,
+			LinkedAccountID: "testLinkedAccountID",
+			DeviceID:        "testDeviceID",
+			Href:            "testHref",
+			SigningSecret:   "testSigningSecret2",
+		},
+		store.Subscription{
+			SubscriptionID:  "3",
+			Type:            store.Type_Devices,
+			LinkedAccountID: "testLinkedAccountID2",
+			SigningSecret:   "testSigningSecret3",
+		},
+		store.Subscription{
+			SubscriptionID:  "4",
+			Type:            store.Type_Device,
+			LinkedAccountID: "testLinkedAccountID2",
+			DeviceID:        "testDeviceID2",
+			SigningSecret:   "testSigningSecret4",
+		},
+	}
+}
+
+func testFindOrCreateSubscription(t *testing.T, newStore NewStoreFunc) {
+	subs := newTestSubscriptions()
+	tests := []struct {
+		name    string
+		sub     store.Subscription
+		want    store.Subscription
+		wantErr bool
+	}{
+		{
+			name:    "invalid SubscriptionID",
+			sub:     store.Subscription{Type: store.Type_Devices, LinkedAccountID: "testLinkedAccountID"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid LinkedAccountID",
+			sub:     store.Subscription{SubscriptionID: "0", Type: store.Type_Devices},
+			wantErr: true,
+		},
+		{
+			name:    "invalid Type",
+			sub:     store.Subscription{SubscriptionID: "0", LinkedAccountID: "testLinkedAccountID"},
+			wantErr: true,
+		},
+		{
+			name:    "Type_Device - invalid DeviceID",
+			sub:     store.Subscription{SubscriptionID: "1", Type: store.Type_Device, LinkedAccountID: "testLinkedAccountID"},
+			wantErr: true,
+		},
+		{
+			name:    "Type_Resource - invalid Href",
+			sub:     store.Subscription{SubscriptionID: "2", Type: store.Type_Resource, LinkedAccountID: "testLinkedAccountID", DeviceID: "testDeviceID"},
+			wantErr: true,
+		},
+		{
+			name: "Type_Devices - valid",
+			sub:  subs[0],
+			want: subs[0],
+		},
+		{
+			name: "Type_Devices - valid - duplicit with same SubscriptionID",
+			sub:  subs[0],
+			want: subs[0],
+		},
+		{
+			name: "Type_Devices - error - duplicit with different SubscriptionID",
+			sub: store.Subscription{
+				SubscriptionID:  "1",
+				Type:            store.Type_Devices,
+				LinkedAccountID: "testLinkedAccountID",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Device - valid",
+			sub:  subs[1],
+			want: subs[1],
+		},
+		{
+			name: "Type_Device - valid - duplicit with same SubscriptionID",
+			sub:  subs[1],
+			want: subs[1],
+		},
+		{
+			name: "Type_Device - error - duplicit with different SubscriptionID",
+			sub: store.Subscription{
+				SubscriptionID:  "2",
+				Type:            store.Type_Device,
+				LinkedAccountID: "testLinkedAccountID",
+				DeviceID:        "testDeviceID",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Resource - valid",
+			sub:  subs[2],
+			want: subs[2],
+		},
+		{
+			name: "Type_Resource - valid - duplicit with same SubscriptionID",
+			sub:  subs[2],
+			want: subs[2],
+		},
+		{
+			name: "Type_Resource - error - duplicit with different SubscriptionID",
+			sub: store.Subscription{
+				SubscriptionID:  "3",
+				Type:            store.Type_Resource,
+				LinkedAccountID: "testLinkedAccountID",
+				DeviceID:        "testDeviceID",
+				Href:            "testHref",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Type_Devices - valid - other linked account",
+			sub:  subs[3],
+			want: subs[3],
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.FindOrCreateSubscription(ctx, tt.sub)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+			}
+		})
+	}
+	assert.ElementsMatch(t, subs[:4], loadSubscriptions(ctx, t, s))
+}
+
+func testLoadSubscriptions(t *testing.T, newStore NewStoreFunc) {
+	subs := newTestSubscriptions()
+	tests := []struct {
+		name    string
+		queries []store.SubscriptionQuery
+		wantErr bool
+		want    []store.Subscription
+	}{
+		{
+			name: "all",
+			want: subs,
+		},
+		{
+			name:    "bySubscriptionID",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: "2"}},
+			want:    []store.Subscription{subs[2]},
+		},
+		{
+			name:    "byLinkedAccountID",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{LinkedAccountID: "testLinkedAccountID"}},
+			want:    subs[:3],
+		},
+		{
+			name:    "byType",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{Type: store.Type_Device}},
+			want:    []store.Subscription{subs[1], subs[4]},
+		},
+		{
+			name:    "byDevice",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{Type: store.Type_Device, DeviceID: "testDeviceID"}},
+			want:    []store.Subscription{subs[1]},
+		},
+		{
+			name:    "byResource",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{Type: store.Type_Resource, DeviceID: "testDeviceID", Href: "testHref"}},
+			want:    []store.Subscription{subs[2]},
+		},
+		{
+			name:    "byLinkedAccountIDAndType",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{LinkedAccountID: "testLinkedAccountID2", Type: store.Type_Devices}},
+			want:    []store.Subscription{subs[3]},
+		},
+		{
+			name: "or",
+			queries: []store.SubscriptionQuery{
+				store.SubscriptionQuery{SubscriptionID: "0"},
+				store.SubscriptionQuery{Type: store.Type_Device, DeviceID: "testDeviceID2"},
+				store.SubscriptionQuery{Type: store.Type_Resource, DeviceID: "testDeviceID", Href: "testHref"},
+			},
+			want: []store.Subscription{subs[0], subs[2], subs[4]},
+		},
+		{
+			name:    "not found",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{SubscriptionID: "notFound"}},
+		},
+		{
+			name:    "invalid - DeviceID without Type",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{DeviceID: "testDeviceID"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid - Href without DeviceID",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{Type: store.Type_Resource, Href: "testHref"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid - Href without Type",
+			queries: []store.SubscriptionQuery{store.SubscriptionQuery{DeviceID: "testDeviceID", Href: "testHref"}},
+			wantErr: true,
+		},
+		{
+			name: "invalid - one of queries",
+			queries: []store.SubscriptionQuery{
+				store.SubscriptionQuery{SubscriptionID: "0"},
+				store.SubscriptionQuery{DeviceID: "testDeviceID"},
+			},
+			wantErr: true,
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, sub := range subs {
+		_, err := s.FindOrCreateSubscription(ctx, sub)
+		require.NoError(t, err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h subscriptionHandler
+			err := s.LoadSubscriptions(ctx, tt.queries, &h)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.ElementsMatch(t, tt.want, h.subs)
+			}
+		})
+	}
+}
+
+func testRemoveSubscriptions(t *testing.T, newStore NewStoreFunc) {
+	subs := newTestSubscriptions()
+	tests := []struct {
+		name    string
+		query   store.SubscriptionQuery
+		wantErr bool
+		want    []store.Subscription
+	}{
+		{
+			name:    "byDeviceID",
+			query:   store.SubscriptionQuery{DeviceID: "testDeviceID"},
+			wantErr: true,
+			want:    subs,
+		},
+		{
+			name:    "byHref",
+			query:   store.SubscriptionQuery{Href: "testHref"},
+			wantErr: true,
+			want:    subs,
+		},
+		{
+			name:    "byType",
+			query:   store.SubscriptionQuery{Type: store.Type_Devices},
+			wantErr: true,
+			want:    subs,
+		},
+		{
+			name:  "bySubscriptionID",
+			query: store.SubscriptionQuery{SubscriptionID: "4"},
+			want:  subs[:4],
+		},
+		{
+			name:  "bySubscriptionID - not found",
+			query: store.SubscriptionQuery{SubscriptionID: "notFound"},
+			want:  subs[:4],
+		},
+		{
+			name:  "byLinkedAccountID",
+			query: store.SubscriptionQuery{LinkedAccountID: "testLinkedAccountID"},
+			want:  []store.Subscription{subs[3]},
+		},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	for _, sub := range subs {
+		_, err := s.FindOrCreateSubscription(ctx, sub)
+		require.NoError(t, err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.RemoveSubscriptions(ctx, tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.ElementsMatch(t, tt.want, loadSubscriptions(ctx, t, s))
+		})
+	}
+}
+
+func testUpdateSubscriptionSequenceNumber(t *testing.T, newStore NewStoreFunc) {
+	sub := newTestSubscriptions()[0]
+	tests := []struct {
+		name           string
+		subscriptionID string
+		sequenceNumber uint64
+		wantStale      bool
+		wantErr        bool
+		want           uint64
+	}{
+		{name: "not found", subscriptionID: "notFound", sequenceNumber: 0, wantErr: true},
+		{name: "first event", subscriptionID: sub.SubscriptionID, sequenceNumber: 0, want: 1},
+		{name: "duplicate", subscriptionID: sub.SubscriptionID, sequenceNumber: 0, wantStale: true, want: 1},
+		{name: "gap", subscriptionID: sub.SubscriptionID, sequenceNumber: 5, want: 6},
+		{name: "lower than accepted", subscriptionID: sub.SubscriptionID, sequenceNumber: 3, wantStale: true, want: 6},
+		{name: "next", subscriptionID: sub.SubscriptionID, sequenceNumber: 6, want: 7},
+	}
+
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	_, err := s.FindOrCreateSubscription(ctx, sub)
+	require.NoError(t, err)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.UpdateSubscriptionSequenceNumber(ctx, tt.subscriptionID, tt.sequenceNumber)
+			switch {
+			case tt.wantStale:
+				require.Error(t, err)
+				assert.True(t, store.IsStaleSequenceNumberError(err))
+			case tt.wantErr:
+				require.Error(t, err)
+				assert.False(t, store.IsStaleSequenceNumberError(err))
+				return
+			default:
+				require.NoError(t, err)
+			}
+			subs := loadSubscriptions(ctx, t, s, store.SubscriptionQuery{SubscriptionID: tt.subscriptionID})
+			require.Len(t, subs, 1)
+			assert.Equal(t, tt.want, subs[0].NextSequenceNumber)
+		})
+	}
+}
+
+func testUpdateSubscriptionSigningSecret(t *testing.T, newStore NewStoreFunc) {
+	ctx := context.Background()
+	s, cleanUp := newStore(t)
+	defer cleanUp()
+
+	sub := newTestSubscriptions()[0]
+	_, err := s.FindOrCreateSubscription(ctx, sub)
+	require.NoError(t, err)
+	require.NoError(t, s.UpdateSubscriptionSequenceNumber(ctx, sub.SubscriptionID, 4))
+
+	err = s.UpdateSubscriptionSigningSecret(ctx, "notFound", "newSigningSecret")
+	require.Error(t, err)
+
+	require.NoError(t, s.UpdateSubscriptionSigningSecret(ctx, sub.SubscriptionID, "newSigningSecret"))
+	subs := loadSubscriptions(ctx, t, s, store.SubscriptionQuery{SubscriptionID: sub.SubscriptionID})
+	require.Len(t, subs, 1)
+	sub.SigningSecret = "newSigningSecret"
+	sub.NextSequenceNumber = 5
+	assert.Equal(t, sub, subs[0])
+}
@@ -1,5 +1,7 @@
 package store
 
+import "fmt"
+
 type Type string
 
 const (
@@ -15,4 +17,33 @@ type Subscription struct {
 	DeviceID        string
 	Href            string
 	SigningSecret   string
+	// NextSequenceNumber is the lowest Sequence-Number of events accepted for the subscription.
+	// It is zero until the first event is accepted.
+	NextSequenceNumber uint64
+}
+
+// Validate checks that the subscription can be stored.
+func (s Subscription) Validate() error {
+	if s.SubscriptionID == "" {
+		return fmt.Errorf("invalid SubscriptionID")
+	}
+	if s.LinkedAccountID == "" {
+		return fmt.Errorf("invalid LinkedAccountID")
+	}
+	switch s.Type {
+	case "":
+		return fmt.Errorf("invalid Type")
+	case Type_Device:
+		if s.DeviceID == "" {
+			return fmt.Errorf("invalid DeviceID")
+		}
+	case Type_Resource:
+		if s.DeviceID == "" {
+			return fmt.Errorf("invalid DeviceID")
+		}
+		if s.Href == "" {
+			return fmt.Errorf("invalid Href")
+		}
+	}
+	return nil
 }
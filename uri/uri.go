@@ -9,20 +9,33 @@ const (
 	// POST - add linked cloud
 	LinkedClouds string = Version + "/linkedclouds"
 
-	// DELETE - delete linked cloud
+	// DELETE - delete linked cloud - params: cascade (optional) deletes linked accounts of the linked cloud
 	LinkedCloud string = LinkedClouds + "/{{ .LinkedCloudId }}"
 
-	// GET - add linked account - params: target_url, target_linked_cloud_id
+	// GET - add linked account - params: target_url, target_linked_cloud_id, redirect_uri (optional)
+	// POST - add linked account of a client credentials linked cloud - header: Authorization: Bearer <origin cloud access token> - params: target_url, target_linked_cloud_id,
+	// refresh_token (optional) of the origin cloud which renews the origin cloud access token, otherwise the linked account must be reauthorized when it expires
 	LinkedAccounts string = Version + "/linkedaccounts"
+	// POST - add linked account by the device authorization grant (RFC 8628) - params: target_url, target_linked_cloud_id
+	DeviceLinkedAccounts string = LinkedAccounts + "/device"
+	// GET - poll the device authorization, it returns the user code of the current step or LinkedAccountId when the linking is finished
+	DeviceLinkedAccount string = DeviceLinkedAccounts + "/{{ .DeviceAuthorizationId }}"
 	// GET - retrieve all linked accounts
 	RetrieveLinkedAccounts string = Version + "/linkedaccounts/retrieve"
 
 	// DELETE - delete linked account
 	LinkedAccount string = LinkedAccounts + "/{{ .LinkedAccountId }}"
+	// PUT - set status of linked account - body: {"Status": "active" | "disabled"}
+	LinkedAccountStatus string = LinkedAccount + "/status"
+	// GET - reauthorize linked account - params: side (origin | target), redirect_uri (optional)
+	ReauthorizeLinkedAccount string = LinkedAccount + "/reauthorize"
 
 	// POST - new events from target cloud subscriptions
 	NotifyLinkedAccount string = Version + "/linkedaccountsevents"
 
 	// GET
 	OAuthCallback string = Version + "/oauthcallback"
+
+	// GET - retrieve metrics in the expvar format, e.g. gaps of event sequence numbers
+	Metrics string = Version + "/metrics"
 )